@@ -22,7 +22,7 @@ import (
 var buildVersion = "dev"
 
 var (
-	pageTemplate        = mustParseTemplate("page.html", "document.html", "footer.html")
+	pageTemplate        = mustParseTemplate("page.html", "document.html", "footer.html", "theme-preset-preview.html")
 	pageContentTemplate = mustParseTemplate("page-content.html")
 	manifestTemplate    = mustParseTemplate("manifest.json")
 )
@@ -56,6 +56,10 @@ var gzipWriterPool = sync.Pool{
 // isCompressiblePath returns true if the request path should be gzip-compressed.
 // Skips already-compressed binary assets (images, fonts, etc.).
 func isCompressiblePath(path string) bool {
+	// Event streams have to be flushed as they're written
+	if strings.HasSuffix(path, "/events") {
+		return false
+	}
 	if strings.HasPrefix(path, "/static/") {
 		// Only compress text-based static assets
 		if strings.HasSuffix(path, ".css") || strings.HasSuffix(path, ".js") || strings.HasSuffix(path, ".svg") {
@@ -100,6 +104,7 @@ type application struct {
 	parsedManifest []byte
 
 	slugToPage    map[string]*page
+	themes        []*themeProperties
 	widgetByID    map[uint64]widget
 	refreshCancel context.CancelFunc
 	refreshWg     sync.WaitGroup
@@ -115,20 +120,30 @@ func newApplication(c *config) (*application, error) {
 	}
 	config := &app.Config
 
-	config.Theme.Key = "default"
-	if err := config.Theme.init(); err != nil {
-		return nil, fmt.Errorf("initializing default theme: %v", err)
+	if err := app.initThemes(); err != nil {
+		return nil, err
 	}
 
 	app.slugToPage[""] = &config.Pages[0]
 
+	storage, err := newDataStorage(config.Server.DataPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uptimeHistory.useStorage(storage); err != nil {
+		slog.Error("Could not restore monitor history", "error", err)
+	}
+
 	providers := &widgetProviders{
 		assetResolver: app.StaticAssetPath,
+		dataStorage:   storage,
 	}
 
 	for p := range config.Pages {
 		page := &config.Pages[p]
 		page.PrimaryColumnIndex = -1
+		page.events = newPageEvents()
 
 		if page.Slug == "" {
 			page.Slug = titleToSlug(page.Title)
@@ -140,6 +155,17 @@ func newApplication(c *config) (*application, error) {
 
 		app.slugToPage[page.Slug] = page
 
+		if page.Theme != nil {
+			if err := page.Theme.applyTo(&config.Theme.themeProperties); err != nil {
+				return nil, fmt.Errorf("applying theme of page %s: %v", page.Title, err)
+			}
+
+			page.Theme.Key = "page-" + page.Slug
+			if err := page.Theme.init(); err != nil {
+				return nil, fmt.Errorf("initializing theme of page %s: %v", page.Title, err)
+			}
+		}
+
 		if page.Width == "default" {
 			page.Width = ""
 		}
@@ -148,25 +174,22 @@ func newApplication(c *config) (*application, error) {
 			page.DesktopNavigationWidth = page.Width
 		}
 
-		for i := range page.HeadWidgets {
-			widget := page.HeadWidgets[i]
-			app.widgetByID[widget.GetID()] = widget
-			widget.setProviders(providers)
-		}
-
 		for c := range page.Columns {
-			column := &page.Columns[c]
-
-			if page.PrimaryColumnIndex == -1 && column.Size == "full" {
+			if page.PrimaryColumnIndex == -1 && page.Columns[c].Size == "full" {
 				page.PrimaryColumnIndex = int8(c)
 			}
+		}
 
-			for w := range column.Widgets {
-				widget := column.Widgets[w]
-				app.widgetByID[widget.GetID()] = widget
-				widget.setProviders(providers)
+		for i, widget := range page.allWidgets() {
+			app.widgetByID[widget.GetID()] = widget
+			widget.setProviders(providers)
+
+			if monitor, ok := widget.(*monitorWidget); ok {
+				monitor.alertKey = fmt.Sprintf("%s/%d", page.Slug, i)
 			}
 		}
+
+		page.snapshotMetrics()
 	}
 
 	config.Server.BaseURL = strings.TrimRight(config.Server.BaseURL, "/")
@@ -198,6 +221,12 @@ func newApplication(c *config) (*application, error) {
 
 	if config.Branding.AppBackgroundColor == "" {
 		config.Branding.AppBackgroundColor = config.Theme.BackgroundColorAsHex
+
+		// The manifest can only override the colors for dark mode
+		if config.Theme.IsAuto() {
+			config.Branding.AppBackgroundColor = config.Theme.LightBackgroundColorAsHex
+			config.Branding.AppDarkBackgroundColor = config.Theme.BackgroundColorAsHex
+		}
 	}
 
 	manifest, err := executeTemplateToString(manifestTemplate, templateData{App: app})
@@ -209,43 +238,47 @@ func newApplication(c *config) (*application, error) {
 	return app, nil
 }
 
-func (p *page) updateOutdatedWidgets() {
+// updateOutdatedWidgets updates the widgets that are shown on any of the given
+// layouts. Widgets hidden by their schedule or on every layout aren't updated
+// until they're shown.
+func (p *page) updateOutdatedWidgets(layouts widgetLayouts) {
 	now := time.Now()
 
 	var wg sync.WaitGroup
+	var updated []widget
 	ctx := context.Background()
 
-	for w := range p.HeadWidgets {
-		widget := p.HeadWidgets[w]
-
+	for _, widget := range p.visibleWidgets(now, layouts) {
 		if !widget.requiresUpdate(&now) {
 			continue
 		}
 
+		updated = append(updated, widget)
 		wg.Add(1)
-		go func(idx int) {
+		go func() {
 			defer wg.Done()
-			p.HeadWidgets[idx].update(ctx)
-		}(w)
+			updateWidget(ctx, widget)
+		}()
 	}
 
-	for c := range p.Columns {
-		for w := range p.Columns[c].Widgets {
-			widget := p.Columns[c].Widgets[w]
+	wg.Wait()
 
-			if !widget.requiresUpdate(&now) {
-				continue
-			}
+	if len(updated) > 0 {
+		p.events.publishChanged(updated)
+		p.snapshotMetrics()
+	}
 
-			wg.Add(1)
-			go func(colIdx, widIdx int) {
-				defer wg.Done()
-				p.Columns[colIdx].Widgets[widIdx].update(ctx)
-			}(c, w)
-		}
+	// Only changes to the schedule need a reload, the layout is up to the client
+	scheduled := p.visibleWidgets(now, allLayouts)
+	visibleIDs := make([]uint64, len(scheduled))
+	for i, w := range scheduled {
+		visibleIDs[i] = w.GetID()
 	}
 
-	wg.Wait()
+	if p.visibleWidgetIDs != nil && !slices.Equal(p.visibleWidgetIDs, visibleIDs) {
+		p.events.publishReload()
+	}
+	p.visibleWidgetIDs = visibleIDs
 }
 
 func (a *application) resolveUserDefinedAssetPath(path string) string {
@@ -257,7 +290,10 @@ func (a *application) resolveUserDefinedAssetPath(path string) string {
 }
 
 type templateRequestData struct {
-	Theme *themeProperties
+	Theme   *themeProperties
+	Pages   []*page
+	User    *requestIdentity
+	Layouts widgetLayouts
 }
 
 type templateData struct {
@@ -266,12 +302,48 @@ type templateData struct {
 	Request templateRequestData
 }
 
-func (a *application) populateTemplateRequestData(data *templateRequestData, _ *http.Request) {
-	data.Theme = &a.Config.Theme.themeProperties
+func (a *application) populateTemplateRequestData(data *templateRequestData, r *http.Request) {
+	data.Theme = a.themeForRequest(r)
+	data.User = identityFromContext(r.Context())
+	data.Pages = a.accessiblePages(data.User)
+}
+
+func (a *application) accessiblePages(identity *requestIdentity) []*page {
+	pages := make([]*page, 0, len(a.Config.Pages))
+	for i := range a.Config.Pages {
+		if a.Config.Pages[i].isAccessibleBy(identity) {
+			pages = append(pages, &a.Config.Pages[i])
+		}
+	}
+
+	return pages
+}
+
+// pageForRequest returns the requested page if it exists and the requesting
+// user is allowed to see it. An empty slug resolves to the first accessible
+// page rather than always the first configured one.
+func (a *application) pageForRequest(r *http.Request) (*page, bool) {
+	identity := identityFromContext(r.Context())
+	slug := r.PathValue("page")
+
+	if slug == "" {
+		pages := a.accessiblePages(identity)
+		if len(pages) == 0 {
+			return nil, false
+		}
+		return pages[0], true
+	}
+
+	page, exists := a.slugToPage[slug]
+	if !exists || !page.isAccessibleBy(identity) {
+		return nil, false
+	}
+
+	return page, true
 }
 
 func (a *application) handlePageRequest(w http.ResponseWriter, r *http.Request) {
-	page, exists := a.slugToPage[r.PathValue("page")]
+	page, exists := a.pageForRequest(r)
 	if !exists {
 		a.handleNotFound(w, r)
 		return
@@ -283,6 +355,11 @@ func (a *application) handlePageRequest(w http.ResponseWriter, r *http.Request)
 	}
 	a.populateTemplateRequestData(&data.Request, r)
 
+	// Pages with their own theme ignore the one picked by the user
+	if page.Theme != nil {
+		data.Request.Theme = page.Theme.themeProperties
+	}
+
 	var responseBytes bytes.Buffer
 	err := pageTemplate.Execute(&responseBytes, data)
 	if err != nil {
@@ -296,14 +373,16 @@ func (a *application) handlePageRequest(w http.ResponseWriter, r *http.Request)
 }
 
 func (a *application) handlePageContentRequest(w http.ResponseWriter, r *http.Request) {
-	page, exists := a.slugToPage[r.PathValue("page")]
+	page, exists := a.pageForRequest(r)
 	if !exists {
 		a.handleNotFound(w, r)
 		return
 	}
 
+	layouts := layoutsForRequest(r)
 	pageData := templateData{
-		Page: page,
+		Page:    page,
+		Request: templateRequestData{Layouts: layouts},
 	}
 
 	var err error
@@ -347,7 +426,7 @@ func (a *application) handlePageContentRequest(w http.ResponseWriter, r *http.Re
 		// Try to acquire lock; skip if another update is already running
 		if p.mu.TryLock() {
 			defer p.mu.Unlock()
-			p.updateOutdatedWidgets()
+			p.updateOutdatedWidgets(layouts)
 		}
 	}()
 }
@@ -374,37 +453,49 @@ func (a *application) handleNotFound(w http.ResponseWriter, _ *http.Request) {
 
 func (a *application) findWidgetByID(id uint64) (widget, *page) {
 	for _, page := range a.slugToPage {
-		for _, w := range page.HeadWidgets {
+		for _, w := range page.allWidgets() {
 			if w.GetID() == id {
 				return w, page
 			}
 		}
-		for _, col := range page.Columns {
-			for _, w := range col.Widgets {
-				if w.GetID() == id {
-					return w, page
-				}
-			}
-		}
 	}
 	return nil, nil
 }
 
-func (a *application) handleWidgetRequest(w http.ResponseWriter, r *http.Request) {
-	// Parse widget ID from URL
-	widgetIDStr := r.PathValue("widget")
-	widgetID, err := strconv.ParseUint(widgetIDStr, 10, 64)
+// widgetForRequest looks up the widget from the {widget} path value. Widgets
+// on pages the user can't access are reported as not found.
+func (a *application) widgetForRequest(w http.ResponseWriter, r *http.Request) (widget, *page, bool) {
+	widgetID, err := strconv.ParseUint(r.PathValue("widget"), 10, 64)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte("Invalid widget ID"))
-		return
+		return nil, nil, false
 	}
 
-	// Find the widget
 	widget, page := a.findWidgetByID(widgetID)
-	if widget == nil {
+	if widget == nil || !page.isAccessibleBy(identityFromContext(r.Context())) {
 		w.WriteHeader(http.StatusNotFound)
 		w.Write([]byte("Widget not found"))
+		return nil, nil, false
+	}
+
+	return widget, page, true
+}
+
+func (a *application) handleWidgetRequest(w http.ResponseWriter, r *http.Request) {
+	found, page, ok := a.widgetForRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if path := r.PathValue("path"); path != "" {
+		if handler, ok := found.(widgetRequestHandler); ok {
+			handler.handleRequest(w, r, path)
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Not found"))
 		return
 	}
 
@@ -413,12 +504,14 @@ func (a *application) handleWidgetRequest(w http.ResponseWriter, r *http.Request
 	defer cancel()
 
 	page.mu.Lock()
-	widget.update(ctx)
+	updateWidget(ctx, found)
+	page.events.publishChanged([]widget{found})
+	page.snapshotMetrics()
 	page.mu.Unlock()
 
 	// Read widget HTML with read lock
 	page.mu.RLock()
-	html := widget.Render()
+	html := found.Render()
 	page.mu.RUnlock()
 
 	// Return the rendered widget HTML
@@ -427,19 +520,31 @@ func (a *application) handleWidgetRequest(w http.ResponseWriter, r *http.Request
 	w.Write([]byte(html))
 }
 
-const backgroundRefreshInterval = 5 * time.Minute
+const (
+	backgroundRefreshInterval = 5 * time.Minute
+	// Only widgets whose cache expired are updated, so checking pages that are
+	// open more often lets changes reach their event streams soon after they
+	// happen.
+	liveRefreshInterval = 30 * time.Second
+)
 
 func (a *application) runBackgroundRefresh(ctx context.Context) {
 	// Run first refresh immediately so the first page load often has warm caches.
 	a.refreshWg.Add(1)
 	go func() {
 		defer a.refreshWg.Done()
-		a.refreshAllPages()
+		a.refreshPages(false)
 	}()
 
 	ticker := time.NewTicker(backgroundRefreshInterval)
 	defer ticker.Stop()
 
+	liveTicker := time.NewTicker(liveRefreshInterval)
+	defer liveTicker.Stop()
+
+	snapshotTicker := time.NewTicker(uptimeHistorySnapshotInterval)
+	defer snapshotTicker.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -448,14 +553,30 @@ func (a *application) runBackgroundRefresh(ctx context.Context) {
 			a.refreshWg.Add(1)
 			go func() {
 				defer a.refreshWg.Done()
-				a.refreshAllPages()
+				a.refreshPages(false)
 			}()
+		case <-liveTicker.C:
+			a.refreshWg.Add(1)
+			go func() {
+				defer a.refreshWg.Done()
+				a.refreshPages(true)
+			}()
+		case <-snapshotTicker.C:
+			if err := uptimeHistory.snapshot(); err != nil {
+				slog.Error("Could not save monitor history", "error", err)
+			}
 		}
 	}
 }
 
-func (a *application) refreshAllPages() {
+// refreshPages updates the outdated widgets of every page, or only of the
+// pages that someone is subscribed to.
+func (a *application) refreshPages(subscribedOnly bool) {
 	for _, pg := range a.slugToPage {
+		if subscribedOnly && !pg.events.hasSubscribers() {
+			continue
+		}
+
 		func(p *page) {
 			defer func() {
 				if x := recover(); x != nil {
@@ -464,7 +585,7 @@ func (a *application) refreshAllPages() {
 			}()
 			p.mu.Lock()
 			defer p.mu.Unlock()
-			p.updateOutdatedWidgets()
+			p.updateOutdatedWidgets(p.events.subscribedLayouts())
 		}(pg)
 	}
 }
@@ -483,17 +604,32 @@ func (a *application) server() (func() error, func() error) {
 
 	// API routes first so /api/... is never matched by GET /{page}
 	mux.HandleFunc("GET /api/pages/{page}/content/", a.handlePageContentRequest) // {page} can be "" for root
+	mux.HandleFunc("GET /api/pages/{page}/events", a.handlePageEventsRequest)
+	mux.HandleFunc("GET /api/pages/{page}/data", a.handlePageDataRequest)
+	mux.HandleFunc("POST /api/set-theme/{key}", a.handleSetThemeRequest)
 
 	mux.HandleFunc("GET /favicon.ico", a.handleFaviconRedirect)
 
+	if a.Config.Server.Auth.LoginEnabled() {
+		mux.HandleFunc("GET /login", a.handleLoginPageRequest)
+		mux.HandleFunc("POST /login", a.handleLoginRequest)
+		mux.HandleFunc("GET /logout", a.handleLogoutRequest)
+	}
+
 	mux.HandleFunc("GET /{$}", a.handlePageRequest)
 	mux.HandleFunc("GET /{page}", a.handlePageRequest)
 
 	mux.HandleFunc("/api/widgets/{widget}/{path...}", a.handleWidgetRequest)
+	mux.HandleFunc("GET /api/widgets/{widget}/data", a.handleWidgetDataRequest)
+	mux.HandleFunc("GET /api/monitor/{widget}/stats", a.handleMonitorStatsRequest)
 	mux.HandleFunc("GET /api/healthz", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 
+	if a.Config.Server.Metrics.Enabled {
+		mux.HandleFunc("GET /metrics", a.handleMetricsRequest)
+	}
+
 	mux.Handle(
 		fmt.Sprintf("GET /static/%s/{path...}", staticFSHash),
 		http.StripPrefix(
@@ -526,8 +662,17 @@ func (a *application) server() (func() error, func() error) {
 		mux.Handle("/assets/{path...}", http.StripPrefix("/assets/", assetsFS))
 	}
 
-	// Wrap mux with gzip compression middleware
-	handler := gzipMiddleware(mux)
+	var handler http.Handler = mux
+	if a.Config.Server.Auth.Enabled() {
+		handler = a.authMiddleware(handler)
+	}
+
+	// Wrap with gzip compression middleware
+	handler = gzipMiddleware(handler)
+
+	if a.Config.Server.Metrics.Enabled {
+		handler = metricsMiddleware(mux, handler)
+	}
 
 	server := http.Server{
 		Addr:              fmt.Sprintf("%s:%d", a.Config.Server.Host, a.Config.Server.Port),
@@ -537,6 +682,7 @@ func (a *application) server() (func() error, func() error) {
 		WriteTimeout:      30 * time.Second,
 		IdleTimeout:       120 * time.Second,
 	}
+	server.RegisterOnShutdown(a.closePageEvents)
 
 	refreshCtx, refreshCancel := context.WithCancel(context.Background())
 	a.refreshCancel = refreshCancel
@@ -573,6 +719,10 @@ func (a *application) server() (func() error, func() error) {
 		case <-time.After(5 * time.Second):
 			slog.Warn("Background jobs did not complete within timeout, proceeding with shutdown")
 		}
+
+		if err := uptimeHistory.snapshot(); err != nil {
+			slog.Error("Could not save monitor history", "error", err)
+		}
 		
 		// Graceful shutdown with 10 second timeout
 		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -0,0 +1,186 @@
+package dashdashdash
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestAuthConfig(t *testing.T, usernames ...string) *authConfig {
+	t.Helper()
+
+	config := &authConfig{
+		SecretKey: "0123456789abcdef0123456789abcdef",
+		Users:     make(map[string]*authUser),
+	}
+
+	for _, username := range usernames {
+		config.Users[username] = &authUser{PasswordHash: string(dummyPasswordHash)}
+	}
+
+	if err := config.initialize(); err != nil {
+		t.Fatalf("initializing auth config: %v", err)
+	}
+
+	return config
+}
+
+func TestVerifySessionToken(t *testing.T) {
+	config := newTestAuthConfig(t, "admin", "family:kids")
+	other := newTestAuthConfig(t, "admin")
+	other.secretKey = []byte("a different secret key")
+
+	valid := config.createSessionToken("admin", time.Now().Add(time.Hour))
+	encodedPayload, encodedSignature, _ := strings.Cut(valid, ".")
+
+	tamperedPayload := base64.RawURLEncoding.EncodeToString(
+		[]byte(strings.Replace(mustDecodeBase64(t, encodedPayload), "admin", "family:kids", 1)),
+	)
+
+	tests := []struct {
+		name     string
+		token    string
+		username string
+		valid    bool
+	}{
+		{"valid", valid, "admin", true},
+		{"username containing a colon", config.createSessionToken("family:kids", time.Now().Add(time.Hour)), "family:kids", true},
+		{"expired", config.createSessionToken("admin", time.Now().Add(-time.Second)), "", false},
+		{"removed user", config.createSessionToken("removed", time.Now().Add(time.Hour)), "", false},
+		{"tampered payload", tamperedPayload + "." + encodedSignature, "", false},
+		{"tampered signature", encodedPayload + "." + base64.RawURLEncoding.EncodeToString([]byte("signature")), "", false},
+		{"signed with another key", other.createSessionToken("admin", time.Now().Add(time.Hour)), "", false},
+		{"missing signature", encodedPayload, "", false},
+		{"invalid base64", "!!!." + encodedSignature, "", false},
+		{"empty", "", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			username, ok := config.verifySessionToken(test.token)
+			if ok != test.valid || username != test.username {
+				t.Errorf("expected (%q, %t), got (%q, %t)", test.username, test.valid, username, ok)
+			}
+		})
+	}
+}
+
+func mustDecodeBase64(t *testing.T, encoded string) string {
+	t.Helper()
+
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("decoding %q: %v", encoded, err)
+	}
+
+	return string(decoded)
+}
+
+func TestLoginClientIP(t *testing.T) {
+	withProxy := newTestAuthConfig(t, "admin")
+	withProxy.Proxy = &authProxyConfig{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1"}}
+	if err := withProxy.Proxy.initialize(); err != nil {
+		t.Fatalf("initializing proxy config: %v", err)
+	}
+
+	withoutProxy := newTestAuthConfig(t, "admin")
+
+	tests := []struct {
+		name       string
+		config     *authConfig
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		{"direct", withProxy, "203.0.113.5:1234", nil, "203.0.113.5"},
+		{"forwarded by an untrusted peer", withProxy, "203.0.113.5:1234", map[string]string{"X-Forwarded-For": "198.51.100.7"}, "203.0.113.5"},
+		{"forwarded without a proxy configured", withoutProxy, "10.0.0.2:1234", map[string]string{"X-Forwarded-For": "198.51.100.7"}, "10.0.0.2"},
+		{"forwarded by a trusted peer", withProxy, "10.0.0.2:1234", map[string]string{"X-Forwarded-For": "198.51.100.7"}, "198.51.100.7"},
+		{"spoofed entry before the client", withProxy, "10.0.0.2:1234", map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.7"}, "198.51.100.7"},
+		{"chain of trusted proxies", withProxy, "10.0.0.2:1234", map[string]string{"X-Forwarded-For": "198.51.100.7, 192.168.1.1, 10.0.0.3"}, "198.51.100.7"},
+		{"invalid entry", withProxy, "10.0.0.2:1234", map[string]string{"X-Forwarded-For": "not-an-ip, 10.0.0.3"}, "10.0.0.3"},
+		{"real ip from a trusted peer", withProxy, "10.0.0.2:1234", map[string]string{"X-Real-IP": "198.51.100.7"}, "198.51.100.7"},
+		{"real ip from an untrusted peer", withProxy, "203.0.113.5:1234", map[string]string{"X-Real-IP": "198.51.100.7"}, "203.0.113.5"},
+		{"ipv4-mapped ipv6", withProxy, "[::ffff:10.0.0.2]:1234", map[string]string{"X-Forwarded-For": "::ffff:198.51.100.7"}, "198.51.100.7"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/login", nil)
+			r.RemoteAddr = test.remoteAddr
+			for name, value := range test.headers {
+				r.Header.Set(name, value)
+			}
+
+			if ip := test.config.loginClientIP(r); ip != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, ip)
+			}
+		})
+	}
+}
+
+func TestFailedLoginAttempts(t *testing.T) {
+	attempts := &failedLoginAttempts{attempts: make(map[string][]time.Time)}
+
+	for i := range loginMaxFailedAttempts {
+		if attempts.isBlocked("198.51.100.7") {
+			t.Fatalf("blocked after %d attempts", i)
+		}
+		attempts.record("198.51.100.7")
+	}
+
+	if !attempts.isBlocked("198.51.100.7") {
+		t.Errorf("expected to be blocked after %d attempts", loginMaxFailedAttempts)
+	}
+
+	if attempts.isBlocked("203.0.113.5") {
+		t.Error("other clients shouldn't be blocked")
+	}
+
+	// Attempts older than the window no longer count
+	expired := time.Now().Add(-loginFailedAttemptsWindow - time.Second)
+	for i := range attempts.attempts["198.51.100.7"] {
+		attempts.attempts["198.51.100.7"][i] = expired
+	}
+
+	if attempts.isBlocked("198.51.100.7") {
+		t.Error("expected the block to expire with the attempts")
+	}
+
+	if _, exists := attempts.attempts["198.51.100.7"]; exists {
+		t.Error("expected the client to be forgotten once its attempts expired")
+	}
+
+	attempts.record("203.0.113.5")
+	attempts.reset("203.0.113.5")
+	if len(attempts.attempts) != 0 {
+		t.Errorf("expected reset to forget the client, got %v", attempts.attempts)
+	}
+}
+
+func TestFailedLoginAttemptsAreBounded(t *testing.T) {
+	attempts := &failedLoginAttempts{attempts: make(map[string][]time.Time)}
+	now := time.Now()
+
+	for i := range loginMaxTrackedClients {
+		attempts.attempts[fmt.Sprintf("client-%d", i)] = []time.Time{now.Add(time.Duration(i) * time.Millisecond)}
+	}
+	attempts.attempts["oldest"] = []time.Time{now.Add(-time.Minute)}
+
+	attempts.record("198.51.100.7")
+
+	if len(attempts.attempts) > loginMaxTrackedClients {
+		t.Errorf("expected at most %d tracked clients, got %d", loginMaxTrackedClients, len(attempts.attempts))
+	}
+
+	if _, exists := attempts.attempts["oldest"]; exists {
+		t.Error("expected the client with the oldest attempt to be forgotten")
+	}
+
+	if _, exists := attempts.attempts["198.51.100.7"]; !exists {
+		t.Error("expected the new attempt to be tracked")
+	}
+}
@@ -1,8 +1,11 @@
 package dashdashdash
 
 import (
+	"bufio"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
@@ -14,7 +17,9 @@ const (
 	cliIntentServe
 	cliIntentConfigValidate
 	cliIntentConfigPrint
+	cliIntentConfigSchema
 	cliIntentDiagnose
+	cliIntentPasswordHash
 )
 
 type cliOptions struct {
@@ -41,7 +46,9 @@ func parseCliOptions() (*cliOptions, error) {
 		fmt.Println("\nCommands:")
 		fmt.Println("  config:validate       Validate the config file")
 		fmt.Println("  config:print          Print the parsed config file with embedded includes")
+		fmt.Println("  config:schema         Print a JSON Schema of the config file for editors")
 		fmt.Println("  diagnose              Run diagnostic checks")
+		fmt.Println("  password:hash         Read a password from stdin and print its bcrypt hash for use in server.auth.users")
 	}
 
 	configPath := flags.String("config", "config.yml", "Set config path")
@@ -62,11 +69,17 @@ func parseCliOptions() (*cliOptions, error) {
 			intent = cliIntentConfigValidate
 		case "config:print":
 			intent = cliIntentConfigPrint
+		case "config:schema":
+			intent = cliIntentConfigSchema
 		case "diagnose":
 			intent = cliIntentDiagnose
+		case "password:hash":
+			intent = cliIntentPasswordHash
 		default:
 			return nil, unknownCommandErr
 		}
+	} else if args[0] == "password:hash" {
+		return nil, errors.New("password:hash reads the password from stdin, it can't be passed as an argument")
 	} else {
 		return nil, unknownCommandErr
 	}
@@ -78,6 +91,27 @@ func parseCliOptions() (*cliOptions, error) {
 	}, nil
 }
 
+// cliReadPassword reads a password from the first line of stdin, so that it
+// doesn't end up in the shell history or the process list. A prompt is shown
+// when stdin is a terminal.
+func cliReadPassword() (string, error) {
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprint(os.Stderr, "Password: ")
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("password is empty")
+	}
+
+	return password, nil
+}
+
 func cliDiagnose(configPath string) int {
 	ok := true
 	var config *config
@@ -1,8 +1,10 @@
 package dashdashdash
 
 import (
+	"errors"
 	"fmt"
 	"html/template"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
@@ -34,6 +36,8 @@ func (c *hslColorField) ToHex() string {
 }
 
 
+// UnmarshalYAML accepts HSL, #rgb, #rrggbb, rgb() and oklch() colors, which
+// are converted to HSL.
 func (c *hslColorField) UnmarshalYAML(node *yaml.Node) error {
 	var value string
 
@@ -41,44 +45,143 @@ func (c *hslColorField) UnmarshalYAML(node *yaml.Node) error {
 		return err
 	}
 
+	color, err := parseColorField(strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("line %d: invalid color %s: %v", node.Line, value, err)
+	}
+
+	*c = color
+	return nil
+}
+
+var (
+	rgbColorFieldPattern   = regexp.MustCompile(`^rgba?\(\s*([\d\.]+)(%?)(?:\s*,\s*|\s+)([\d\.]+)(%?)(?:\s*,\s*|\s+)([\d\.]+)(%?)\s*\)$`)
+	oklchColorFieldPattern = regexp.MustCompile(`^oklch\(\s*([\d\.]+)(%?)\s+([\d\.]+)(%?)\s+([\d\.]+)(?:deg)?\s*\)$`)
+)
+
+func parseColorField(value string) (hslColorField, error) {
+	lower := strings.ToLower(value)
+
+	switch {
+	case strings.HasPrefix(lower, "#"):
+		r, g, b, err := hexToRGB(lower)
+		if err != nil {
+			return hslColorField{}, err
+		}
+		return hslColorFieldFromRGB(r, g, b), nil
+	case strings.HasPrefix(lower, "rgb"):
+		return parseRGBColorField(lower)
+	case strings.HasPrefix(lower, "oklch"):
+		return parseOKLCHColorField(lower)
+	}
+
+	return parseHSLColorField(value)
+}
+
+func hslColorFieldFromRGB(r, g, b float64) hslColorField {
+	h, s, l := rgbToHSL(r, g, b)
+	return hslColorField{H: h, S: s, L: l}
+}
+
+func parseRGBColorField(value string) (hslColorField, error) {
+	matches := rgbColorFieldPattern.FindStringSubmatch(value)
+	if len(matches) != 7 {
+		return hslColorField{}, errors.New("expected rgb(red, green, blue)")
+	}
+
+	var components [3]float64
+	for i := range components {
+		component, err := strconv.ParseFloat(matches[1+i*2], 64)
+		if err != nil {
+			return hslColorField{}, err
+		}
+
+		if matches[2+i*2] == "%" {
+			component *= 2.55
+		}
+
+		if component > 255 {
+			return hslColorField{}, errors.New("RGB components must be between 0 and 255")
+		}
+
+		components[i] = component
+	}
+
+	return hslColorFieldFromRGB(components[0], components[1], components[2]), nil
+}
+
+func parseOKLCHColorField(value string) (hslColorField, error) {
+	matches := oklchColorFieldPattern.FindStringSubmatch(value)
+	if len(matches) != 6 {
+		return hslColorField{}, errors.New("expected oklch(lightness chroma hue)")
+	}
+
+	lightness, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return hslColorField{}, err
+	}
+
+	if matches[2] == "%" {
+		lightness /= 100
+	}
+
+	if lightness > 1 {
+		return hslColorField{}, errors.New("OKLCH lightness must be between 0 and 1 or 0% and 100%")
+	}
+
+	chroma, err := strconv.ParseFloat(matches[3], 64)
+	if err != nil {
+		return hslColorField{}, err
+	}
+
+	// 100% is a chroma of 0.4 in CSS
+	if matches[4] == "%" {
+		chroma *= 0.004
+	}
+
+	hue, err := strconv.ParseFloat(matches[5], 64)
+	if err != nil {
+		return hslColorField{}, err
+	}
+
+	return hslColorFieldFromRGB(oklchToRGB(lightness, chroma, hue)), nil
+}
+
+func parseHSLColorField(value string) (hslColorField, error) {
 	matches := hslColorFieldPattern.FindStringSubmatch(value)
 
 	if len(matches) != 4 {
-		return fmt.Errorf("invalid HSL color format: %s", value)
+		return hslColorField{}, errors.New("expected HSL such as \"240 8 9\", hex, rgb() or oklch()")
 	}
 
 	hue, err := strconv.ParseFloat(matches[1], 64)
 	if err != nil {
-		return err
+		return hslColorField{}, err
 	}
 
 	if hue > hslHueMax {
-		return fmt.Errorf("HSL hue must be between 0 and %d", hslHueMax)
+		return hslColorField{}, fmt.Errorf("HSL hue must be between 0 and %d", hslHueMax)
 	}
 
 	saturation, err := strconv.ParseFloat(matches[2], 64)
 	if err != nil {
-		return err
+		return hslColorField{}, err
 	}
 
 	if saturation > hslSaturationMax {
-		return fmt.Errorf("HSL saturation must be between 0 and %d", hslSaturationMax)
+		return hslColorField{}, fmt.Errorf("HSL saturation must be between 0 and %d", hslSaturationMax)
 	}
 
 	lightness, err := strconv.ParseFloat(matches[3], 64)
 	if err != nil {
-		return err
+		return hslColorField{}, err
 	}
 
 	if lightness > hslLightnessMax {
-		return fmt.Errorf("HSL lightness must be between 0 and %d", hslLightnessMax)
+		return hslColorField{}, fmt.Errorf("HSL lightness must be between 0 and %d", hslLightnessMax)
 	}
 
-	c.H = hue
-	c.S = saturation
-	c.L = lightness
-
-	return nil
+	return hslColorField{H: hue, S: saturation, L: lightness}, nil
 }
 
 var durationFieldPattern = regexp.MustCompile(`^(\d+)(s|m|h|d)$`)
@@ -92,29 +195,41 @@ func (d *durationField) UnmarshalYAML(node *yaml.Node) error {
 		return err
 	}
 
+	duration, err := parseDurationField(value)
+	if err != nil {
+		return err
+	}
+
+	*d = duration
+	return nil
+}
+
+// parseDurationField parses durations in the format used throughout the
+// config, such as 30s, 5m, 2h or 7d.
+func parseDurationField(value string) (durationField, error) {
 	matches := durationFieldPattern.FindStringSubmatch(value)
 
 	if len(matches) != 3 {
-		return fmt.Errorf("invalid duration format: %s", value)
+		return 0, fmt.Errorf("invalid duration format: %s", value)
 	}
 
 	duration, err := strconv.Atoi(matches[1])
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	switch matches[2] {
 	case "s":
-		*d = durationField(time.Duration(duration) * time.Second)
+		return durationField(time.Duration(duration) * time.Second), nil
 	case "m":
-		*d = durationField(time.Duration(duration) * time.Minute)
+		return durationField(time.Duration(duration) * time.Minute), nil
 	case "h":
-		*d = durationField(time.Duration(duration) * time.Hour)
+		return durationField(time.Duration(duration) * time.Hour), nil
 	case "d":
-		*d = durationField(time.Duration(duration) * 24 * time.Hour)
+		return durationField(time.Duration(duration) * 24 * time.Hour), nil
 	}
 
-	return nil
+	return 0, fmt.Errorf("invalid duration format: %s", value)
 }
 
 type customIconField struct {
@@ -174,3 +289,49 @@ func (i *customIconField) UnmarshalYAML(node *yaml.Node) error {
 	*i = newCustomIconField(value)
 	return nil
 }
+
+// queryParametersField accepts a single value or a list of values for each
+// parameter.
+type queryParametersField map[string][]string
+
+func (q *queryParametersField) UnmarshalYAML(node *yaml.Node) error {
+	var nodes map[string]yaml.Node
+
+	if err := node.Decode(&nodes); err != nil {
+		return err
+	}
+
+	*q = make(queryParametersField, len(nodes))
+
+	for key, node := range nodes {
+		var values []string
+
+		if node.Kind == yaml.SequenceNode {
+			if err := node.Decode(&values); err != nil {
+				return err
+			}
+		} else {
+			var value string
+			if err := node.Decode(&value); err != nil {
+				return err
+			}
+			values = []string{value}
+		}
+
+		(*q)[key] = values
+	}
+
+	return nil
+}
+
+func (q queryParametersField) toQueryString() string {
+	query := url.Values{}
+
+	for key, values := range q {
+		for _, value := range values {
+			query.Add(key, value)
+		}
+	}
+
+	return query.Encode()
+}
@@ -0,0 +1,162 @@
+package dashdashdash
+
+import (
+	"math"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseColorField(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected hslColorField
+		err      string
+	}{
+		{value: "240 13 20", expected: hslColorField{240, 13, 20}},
+		{value: "#f00", expected: hslColorField{0, 100, 50}},
+		{value: "#FF0000", expected: hslColorField{0, 100, 50}},
+		{value: "#808080", expected: hslColorField{0, 0, 50.2}},
+		{value: "#0f0f", err: "expected #rgb or #rrggbb"},
+		{value: "#ggg", err: "expected hexadecimal digits"},
+		{value: "rgb(0, 0, 255)", expected: hslColorField{240, 100, 50}},
+		{value: "rgb(0 255 0)", expected: hslColorField{120, 100, 50}},
+		{value: "rgb(100%, 100%, 100%)", expected: hslColorField{0, 0, 100}},
+		{value: "rgb(255, 255, 255)", expected: hslColorField{0, 0, 100}},
+		{value: "rgb(256, 0, 0)", err: "RGB components must be between 0 and 255"},
+		{value: "rgb(101%, 0%, 0%)", err: "RGB components must be between 0 and 255"},
+		{value: "rgb(0, 0)", err: "expected rgb(red, green, blue)"},
+		{value: "oklch(1 0 0)", expected: hslColorField{0, 0, 100}},
+		{value: "oklch(0% 0 0)", expected: hslColorField{0, 0, 0}},
+		{value: "oklch(0.628 0.2577 29.23deg)", expected: hslColorField{0, 100, 50}},
+		{value: "oklch(1.1 0 0)", err: "OKLCH lightness must be between 0 and 1 or 0% and 100%"},
+		{value: "361 0 0", err: "HSL hue must be between 0 and 360"},
+		{value: "red", err: "expected HSL such as \"240 8 9\", hex, rgb() or oklch()"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			color, err := parseColorField(test.value)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !colorsAreClose(color, test.expected) {
+				t.Errorf("expected %s, got %s", test.expected.String(), color.String())
+			}
+		})
+	}
+}
+
+// Conversions to and from OKLCH are only accurate to a rounding error, which
+// can put a red hue either just above 0 or just below 360.
+func colorsAreClose(a, b hslColorField) bool {
+	const tolerance = 0.5
+	hueDistance := math.Abs(a.H - b.H)
+	hueDistance = math.Min(hueDistance, 360-hueDistance)
+
+	return hueDistance <= tolerance && math.Abs(a.S-b.S) <= tolerance && math.Abs(a.L-b.L) <= tolerance
+}
+
+func TestOKLCHToRGBClampsOutOfGamutColors(t *testing.T) {
+	tests := []struct {
+		name                    string
+		lightness, chroma       float64
+		hue                     float64
+		clampedLow, clampedHigh bool
+	}{
+		{"vivid green", 0.9, 0.4, 145, true, true},
+		{"vivid blue", 0.4, 0.4, 265, true, true},
+		{"brighter than white", 1, 0.2, 0, false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, g, b := oklchToRGB(test.lightness, test.chroma, test.hue)
+
+			var low, high bool
+			for _, component := range []float64{r, g, b} {
+				if component < 0 || component > 255 {
+					t.Fatalf("component %f is outside of 0-255", component)
+				}
+				low = low || component == 0
+				high = high || component == 255
+			}
+
+			if test.clampedLow && !low {
+				t.Errorf("expected a component to be clamped to 0, got %f %f %f", r, g, b)
+			}
+			if test.clampedHigh && !high {
+				t.Errorf("expected a component to be clamped to 255, got %f %f %f", r, g, b)
+			}
+		})
+	}
+}
+
+func TestHexToRGB(t *testing.T) {
+	tests := []struct {
+		hex     string
+		r, g, b float64
+	}{
+		{"#000", 0, 0, 0},
+		{"#abc", 0xaa, 0xbb, 0xcc},
+		{"#1e90ff", 0x1e, 0x90, 0xff},
+	}
+
+	for _, test := range tests {
+		r, g, b, err := hexToRGB(test.hex)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.hex, err)
+			continue
+		}
+
+		if r != test.r || g != test.g || b != test.b {
+			t.Errorf("%s: expected %v %v %v, got %v %v %v", test.hex, test.r, test.g, test.b, r, g, b)
+		}
+	}
+}
+
+func TestRGBToHSL(t *testing.T) {
+	tests := []struct {
+		r, g, b float64
+		h, s, l float64
+	}{
+		{0, 0, 0, 0, 0, 0},
+		{255, 255, 255, 0, 0, 100},
+		{255, 0, 0, 0, 100, 50},
+		{0, 255, 0, 120, 100, 50},
+		{0, 0, 255, 240, 100, 50},
+		{255, 0, 255, 300, 100, 50},
+		{30, 144, 255, 209.6, 100, 55.9},
+	}
+
+	for _, test := range tests {
+		h, s, l := rgbToHSL(test.r, test.g, test.b)
+		if !colorsAreClose(hslColorField{h, s, l}, hslColorField{test.h, test.s, test.l}) {
+			t.Errorf("rgb(%v, %v, %v): expected %v %v %v, got %v %v %v", test.r, test.g, test.b, test.h, test.s, test.l, h, s, l)
+		}
+	}
+}
+
+func TestHSLColorFieldErrorIncludesLine(t *testing.T) {
+	var config struct {
+		Theme struct {
+			BackgroundColor hslColorField `yaml:"background-color"`
+			PrimaryColor    hslColorField `yaml:"primary-color"`
+		} `yaml:"theme"`
+	}
+
+	err := yaml.Unmarshal([]byte("theme:\n  background-color: \"#000\"\n  primary-color: rgb(300, 0, 0)\n"), &config)
+
+	expected := "line 3: invalid color rgb(300, 0, 0): RGB components must be between 0 and 255"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
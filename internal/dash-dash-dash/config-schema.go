@@ -0,0 +1,337 @@
+package dashdashdash
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const configSchemaVersion = "http://json-schema.org/draft-07/schema#"
+
+// jsonSchema is the subset of JSON Schema needed to describe the config file.
+// Draft 7 is used since it's the one supported by most editors.
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 any                    `json:"type,omitempty"`
+	Const                string                 `json:"const,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	PatternProperties    map[string]*jsonSchema `json:"patternProperties,omitempty"`
+	AdditionalProperties any                    `json:"additionalProperties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	AnyOf                []*jsonSchema          `json:"anyOf,omitempty"`
+	AllOf                []*jsonSchema          `json:"allOf,omitempty"`
+	If                   *jsonSchema            `json:"if,omitempty"`
+	Then                 *jsonSchema            `json:"then,omitempty"`
+	Definitions          map[string]*jsonSchema `json:"definitions,omitempty"`
+}
+
+// configSchemaGenerator builds a JSON Schema of the config by reflecting over
+// the yaml tags of its structs. Named structs are added as definitions, which
+// keeps the output small and allows recursive types like themeProperties.
+type configSchemaGenerator struct {
+	definitions map[string]*jsonSchema
+}
+
+var (
+	yamlUnmarshalerType = reflect.TypeFor[yaml.Unmarshaler]()
+	configPackagePath   = reflect.TypeFor[config]().PkgPath()
+)
+
+const includeSchemaDefinition = "include"
+
+// newConfigSchema returns a JSON Schema of the config file, including the
+// widgets registered at the time it's called.
+func newConfigSchema() *jsonSchema {
+	g := &configSchemaGenerator{definitions: make(map[string]*jsonSchema)}
+
+	g.definitions[includeSchemaDefinition] = &jsonSchema{
+		Description: "Replaced with the contents of the given file",
+		Type:        "object",
+		Properties: map[string]*jsonSchema{
+			"$include": {Type: "string"},
+		},
+		Required:             []string{"$include"},
+		AdditionalProperties: false,
+	}
+
+	schema := g.structSchema(reflect.TypeFor[config]())
+	schema.Schema = configSchemaVersion
+	schema.Title = "dash-dash-dash config"
+	schema.Definitions = g.definitions
+
+	return schema
+}
+
+func definitionRef(name string) *jsonSchema {
+	return &jsonSchema{Ref: "#/definitions/" + name}
+}
+
+func (g *configSchemaGenerator) schemaFor(t reflect.Type) *jsonSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if schema := g.customTypeSchema(t); schema != nil {
+		return schema
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &jsonSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &jsonSchema{Type: "number"}
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &jsonSchema{Type: "array", Items: g.itemSchema(t.Elem())}
+	case reflect.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+
+		name := t.Name()
+		if t.PkgPath() != configPackagePath {
+			name = t.String()
+		}
+
+		if _, exists := g.definitions[name]; !exists {
+			// Added before the struct is walked so that it can refer to itself
+			g.definitions[name] = &jsonSchema{}
+			*g.definitions[name] = *g.structSchema(t)
+		}
+
+		return definitionRef(name)
+	}
+
+	return &jsonSchema{}
+}
+
+// itemSchema allows includes in place of list items that are objects.
+func (g *configSchemaGenerator) itemSchema(t reflect.Type) *jsonSchema {
+	schema := g.schemaFor(t)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct && t.Kind() != reflect.Map {
+		return schema
+	}
+
+	return &jsonSchema{AnyOf: []*jsonSchema{definitionRef(includeSchemaDefinition), schema}}
+}
+
+// customTypeSchema describes the types that are decoded from a different
+// shape than their Go type, or returns nil for everything else.
+func (g *configSchemaGenerator) customTypeSchema(t reflect.Type) *jsonSchema {
+	switch t {
+	case reflect.TypeFor[hslColorField]():
+		return &jsonSchema{
+			Type:        "string",
+			Description: "A color as HSL, such as 240 13 20, #rrggbb, rgb() or oklch()",
+		}
+	case reflect.TypeFor[durationField]():
+		return &jsonSchema{
+			Type:        "string",
+			Description: "A duration such as 30s, 5m, 2h or 7d",
+			Pattern:     durationFieldPattern.String(),
+		}
+	case reflect.TypeFor[customIconField]():
+		return &jsonSchema{
+			Type:        "string",
+			Description: "An icon URL or a prefixed name such as si:github, di:, mdi: or sh:",
+		}
+	case reflect.TypeFor[queryParametersField]():
+		value := &jsonSchema{Type: "string"}
+		return &jsonSchema{
+			Type: "object",
+			AdditionalProperties: &jsonSchema{
+				AnyOf: []*jsonSchema{value, {Type: "array", Items: value}},
+			},
+		}
+	case reflect.TypeFor[widgetSchedule]():
+		timeOfDay := &jsonSchema{Type: "string", Pattern: `^\d{1,2}:\d{2}$`}
+		days := make([]string, 0, len(weekdaysByName))
+		for name := range weekdaysByName {
+			days = append(days, name)
+		}
+		slices.Sort(days)
+
+		return &jsonSchema{
+			Type: "object",
+			Properties: map[string]*jsonSchema{
+				"days":     {Type: "array", Items: &jsonSchema{Type: "string", Enum: days}},
+				"from":     timeOfDay,
+				"to":       timeOfDay,
+				"timezone": {Type: "string"},
+			},
+			AdditionalProperties: false,
+		}
+	case reflect.TypeFor[widgetDevices]():
+		return &jsonSchema{
+			Type:  "array",
+			Items: &jsonSchema{Type: "string", Enum: []string{"mobile", "desktop"}},
+		}
+	case reflect.TypeFor[pageTheme]():
+		return g.schemaFor(reflect.TypeFor[themeProperties]())
+	case reflect.TypeFor[yaml.Node]():
+		// Decoded later, any value is accepted
+		return &jsonSchema{}
+	case reflect.TypeFor[notifiers]():
+		return &jsonSchema{Type: "object", AdditionalProperties: g.notifierSchema()}
+	case reflect.TypeFor[widgets]():
+		return &jsonSchema{Type: "array", Items: g.widgetSchema()}
+	}
+
+	if strings.HasPrefix(t.Name(), "orderedYAMLMap[") {
+		get, _ := reflect.PointerTo(t).MethodByName("Get")
+		return &jsonSchema{Type: "object", AdditionalProperties: g.schemaFor(get.Type.Out(0))}
+	}
+
+	// Other types with their own decoding can't be described from their fields
+	if t.Kind() != reflect.Interface && reflect.PointerTo(t).Implements(yamlUnmarshalerType) {
+		return &jsonSchema{}
+	}
+
+	return nil
+}
+
+// structSchema describes the fields of a struct. Null is accepted as well since
+// sections are often left with only commented out properties, which the yaml
+// package decodes as empty.
+func (g *configSchemaGenerator) structSchema(t reflect.Type) *jsonSchema {
+	schema := &jsonSchema{
+		Type:       []string{"object", "null"},
+		Properties: make(map[string]*jsonSchema),
+		PatternProperties: map[string]*jsonSchema{
+			`^\$include$`: {Type: "string"},
+		},
+		AdditionalProperties: false,
+	}
+
+	g.addStructProperties(schema, t)
+	return schema
+}
+
+// addStructProperties follows the rules of the yaml package for which fields
+// are decoded and under which names.
+func (g *configSchemaGenerator) addStructProperties(schema *jsonSchema, t reflect.Type) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("yaml")
+
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		name, flags, _ := strings.Cut(tag, ",")
+		if slices.Contains(strings.Split(flags, ","), "inline") {
+			g.addStructProperties(schema, field.Type)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		schema.Properties[name] = g.schemaFor(field.Type)
+	}
+}
+
+// notifierSchema picks the properties of a notifier based on its type.
+func (g *configSchemaGenerator) notifierSchema() *jsonSchema {
+	if _, exists := g.definitions["notifier"]; exists {
+		return definitionRef("notifier")
+	}
+
+	types := slices.Sorted(maps.Keys(notifierFactories))
+	schema := &jsonSchema{
+		Type:       "object",
+		Properties: map[string]*jsonSchema{"type": {Type: "string", Enum: types}},
+		Required:   []string{"type"},
+	}
+
+	for _, name := range types {
+		properties := g.structSchema(reflect.TypeOf(notifierFactories[name]()))
+		properties.Properties["type"] = &jsonSchema{Const: name}
+		g.definitions["notifier-"+name] = properties
+
+		schema.AllOf = append(schema.AllOf, typeCondition(name, "notifier-"+name))
+	}
+
+	g.definitions["notifier"] = schema
+	return definitionRef("notifier")
+}
+
+// widgetSchema picks the properties of a widget based on its type, plugin
+// widgets are described by the fields of both widgetBase and the plugin.
+func (g *configSchemaGenerator) widgetSchema() *jsonSchema {
+	if _, exists := g.definitions["widget"]; exists {
+		return definitionRef("widget")
+	}
+
+	types := registeredWidgetTypes()
+	schema := &jsonSchema{
+		Type:       "object",
+		Properties: map[string]*jsonSchema{"type": {Type: "string", Enum: types}},
+		Required:   []string{"type"},
+	}
+
+	// Added before the widgets are walked since groups contain widgets
+	g.definitions["widget"] = &jsonSchema{
+		AnyOf: []*jsonSchema{definitionRef(includeSchemaDefinition), schema},
+	}
+
+	for _, name := range types {
+		widget, err := newWidget(name)
+		if err != nil {
+			continue
+		}
+
+		properties := g.structSchema(reflect.TypeOf(widget))
+		if plugin, ok := widget.(*pluginWidget); ok {
+			g.addStructProperties(properties, reflect.TypeOf(plugin.plugin))
+		}
+		properties.Properties["type"] = &jsonSchema{Const: name}
+		g.definitions["widget-"+name] = properties
+
+		schema.AllOf = append(schema.AllOf, typeCondition(name, "widget-"+name))
+	}
+
+	return definitionRef("widget")
+}
+
+func typeCondition(name string, definition string) *jsonSchema {
+	return &jsonSchema{
+		If: &jsonSchema{
+			Properties: map[string]*jsonSchema{"type": {Const: name}},
+			Required:   []string{"type"},
+		},
+		Then: definitionRef(definition),
+	}
+}
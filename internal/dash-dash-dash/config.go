@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -28,11 +29,14 @@ const (
 
 type config struct {
 	Server struct {
-		Host       string `yaml:"host"`
-		Port       uint16 `yaml:"port"`
-		AssetsPath string `yaml:"assets-path"`
-		BaseURL    string `yaml:"base-url"`
-		BasePath   string `yaml:"-"` // path component of BaseURL, for relative asset/API URLs (avoids CORS when opening via 127.0.0.1 vs localhost)
+		Host       string        `yaml:"host"`
+		Port       uint16        `yaml:"port"`
+		AssetsPath string        `yaml:"assets-path"`
+		BaseURL    string        `yaml:"base-url"`
+		BasePath   string        `yaml:"-"` // path component of BaseURL, for relative asset/API URLs (avoids CORS when opening via 127.0.0.1 vs localhost)
+		DataPath   string        `yaml:"data-path"`
+		Auth       authConfig    `yaml:"auth"`
+		Metrics    metricsConfig `yaml:"metrics"`
 	} `yaml:"server"`
 
 	Document struct {
@@ -41,38 +45,73 @@ type config struct {
 
 	Theme struct {
 		themeProperties `yaml:",inline"`
-		CustomCSSFile   string `yaml:"custom-css-file"`
+		CustomCSSFile   string                                   `yaml:"custom-css-file"`
+		Presets         orderedYAMLMap[string, *themeProperties] `yaml:"presets"`
 	} `yaml:"theme"`
 
 	Branding struct {
-		Footer               string        `yaml:"footer"`
-		LogoText             string        `yaml:"logo-text"`
-		LogoURL              string        `yaml:"logo-url"`
-		FaviconURL           string        `yaml:"favicon-url"`
-		FaviconType          string        `yaml:"-"`
-		AppName              string        `yaml:"app-name"`
-		AppIconURL           string        `yaml:"app-icon-url"`
-		AppBackgroundColor    string        `yaml:"app-background-color"`
+		Footer                 string `yaml:"footer"`
+		LogoText               string `yaml:"logo-text"`
+		LogoURL                string `yaml:"logo-url"`
+		FaviconURL             string `yaml:"favicon-url"`
+		FaviconType            string `yaml:"-"`
+		AppName                string `yaml:"app-name"`
+		AppIconURL             string `yaml:"app-icon-url"`
+		AppBackgroundColor     string `yaml:"app-background-color"`
+		AppDarkBackgroundColor string `yaml:"-"` // set when the theme follows the OS
 	} `yaml:"branding"`
 
+	Notifications notifiers `yaml:"notifications"`
+
 	Pages []page `yaml:"pages"`
 }
 
 type page struct {
-	Title                  string  `yaml:"name"`
-	Slug                   string  `yaml:"slug"`
-	Width                  string  `yaml:"width"`
-	DesktopNavigationWidth string  `yaml:"desktop-navigation-width"`
-	ShowMobileHeader       bool    `yaml:"show-mobile-header"`
-	HideDesktopNavigation  bool    `yaml:"hide-desktop-navigation"`
-	CenterVertically       bool    `yaml:"center-vertically"`
-	HeadWidgets            widgets `yaml:"head-widgets"`
+	Title                  string     `yaml:"name"`
+	Slug                   string     `yaml:"slug"`
+	Width                  string     `yaml:"width"`
+	DesktopNavigationWidth string     `yaml:"desktop-navigation-width"`
+	ShowMobileHeader       bool       `yaml:"show-mobile-header"`
+	HideDesktopNavigation  bool       `yaml:"hide-desktop-navigation"`
+	CenterVertically       bool       `yaml:"center-vertically"`
+	AllowedUsers           []string   `yaml:"allowed-users"`
+	AllowedGroups          []string   `yaml:"allowed-groups"`
+	Theme                  *pageTheme `yaml:"theme"`
+	HeadWidgets            widgets    `yaml:"head-widgets"`
 	Columns                []struct {
 		Size    string  `yaml:"size"`
 		Widgets widgets `yaml:"widgets"`
 	} `yaml:"columns"`
-	PrimaryColumnIndex int8       `yaml:"-"`
+	PrimaryColumnIndex int8         `yaml:"-"`
 	mu                 sync.RWMutex `yaml:"-"`
+	events             *pageEvents  `yaml:"-"`
+	visibleWidgetIDs   []uint64     `yaml:"-"` // as of the last update, to notice schedule changes
+	metrics            pageMetrics  `yaml:"-"`
+}
+
+// isAccessibleBy reports whether the page can be viewed by the given identity.
+// Pages without an allow list are visible to everyone who can access the
+// dashboard, pages with one are hidden from anonymous requests.
+func (p *page) isAccessibleBy(identity *requestIdentity) bool {
+	if len(p.AllowedUsers) == 0 && len(p.AllowedGroups) == 0 {
+		return true
+	}
+
+	if identity == nil {
+		return false
+	}
+
+	if slices.Contains(p.AllowedUsers, identity.Username) {
+		return true
+	}
+
+	for _, group := range identity.Groups {
+		if slices.Contains(p.AllowedGroups, group) {
+			return true
+		}
+	}
+
+	return false
 }
 
 func newConfigFromYAML(contents []byte) (*config, error) {
@@ -93,6 +132,10 @@ func newConfigFromYAML(contents []byte) (*config, error) {
 		return nil, err
 	}
 
+	if err = config.Server.Auth.initialize(); err != nil {
+		return nil, fmt.Errorf("server auth: %v", err)
+	}
+
 	for p := range config.Pages {
 		for w := range config.Pages[p].HeadWidgets {
 			if err := config.Pages[p].HeadWidgets[w].initialize(); err != nil {
@@ -109,9 +152,27 @@ func newConfigFromYAML(contents []byte) (*config, error) {
 		}
 	}
 
+	if err = resolveWidgetNotifiers(config); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
 
+func resolveWidgetNotifiers(config *config) error {
+	for p := range config.Pages {
+		for _, w := range config.Pages[p].allWidgets() {
+			if monitor, ok := w.(*monitorWidget); ok {
+				if err := monitor.resolveNotifiers(config.Notifications); err != nil {
+					return formatWidgetInitError(err, w)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
 var envVariableNamePattern = regexp.MustCompile(`^[A-Z0-9_]+$`)
 var configVariablePattern = regexp.MustCompile(`(^|.)\$\{(?:([a-zA-Z]+):)?([a-zA-Z0-9_-]+)\}`)
 
@@ -436,6 +497,10 @@ func isConfigStateValid(config *config) error {
 			return fmt.Errorf("page %d has no columns", i+1)
 		}
 
+		if (len(page.AllowedUsers) > 0 || len(page.AllowedGroups) > 0) && !config.Server.Auth.Enabled() {
+			return fmt.Errorf("page %d: allowed-users and allowed-groups require server.auth to be configured", i+1)
+		}
+
 		if page.Width == "slim" {
 			if len(page.Columns) > 2 {
 				return fmt.Errorf("page %d is slim and cannot have more than 2 columns", i+1)
@@ -0,0 +1,138 @@
+package dashdashdash
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// widgetDataProvider is implemented by widgets that expose their state at
+// /api/widgets/{widget}/data. The data must only contain what the widget
+// shows, never credentials, request headers or other config secrets.
+type widgetDataProvider interface {
+	data() any
+}
+
+// widgetState is the part of the data response that's shared by all widgets.
+type widgetState struct {
+	ID         uint64     `json:"id"`
+	Type       string     `json:"type"`
+	Title      string     `json:"title"`
+	Error      *string    `json:"error"`
+	Notice     *string    `json:"notice"`
+	NextUpdate *time.Time `json:"next_update"`
+}
+
+func (w *widgetBase) state() widgetState {
+	state := widgetState{
+		ID:    w.ID,
+		Type:  w.Type,
+		Title: w.Title,
+	}
+
+	if w.Error != nil {
+		message := w.Error.Error()
+		state.Error = &message
+	}
+
+	if w.Notice != nil {
+		message := w.Notice.Error()
+		state.Notice = &message
+	}
+
+	if !w.nextUpdate.IsZero() {
+		nextUpdate := w.nextUpdate
+		state.NextUpdate = &nextUpdate
+	}
+
+	return state
+}
+
+type widgetDataResponse struct {
+	widgetState
+	Data any `json:"data"`
+}
+
+func newWidgetDataResponse(w widget) widgetDataResponse {
+	response := widgetDataResponse{widgetState: w.state()}
+	if provider, ok := w.(widgetDataProvider); ok {
+		response.Data = provider.data()
+	}
+
+	return response
+}
+
+type pageDataResponse struct {
+	Slug        string               `json:"slug"`
+	Title       string               `json:"title"`
+	HeadWidgets []widgetDataResponse `json:"head_widgets"`
+	Columns     []columnDataResponse `json:"columns"`
+}
+
+type columnDataResponse struct {
+	Size    string               `json:"size"`
+	Widgets []widgetDataResponse `json:"widgets"`
+}
+
+func (a *application) handleWidgetDataRequest(w http.ResponseWriter, r *http.Request) {
+	found, page, ok := a.widgetForRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Encoded while the page is locked since the data shares slices with
+	// the widget, which the next update may modify
+	page.mu.RLock()
+	encoded, err := json.Marshal(newWidgetDataResponse(found))
+	page.mu.RUnlock()
+
+	writeEncodedDataResponse(w, encoded, err)
+}
+
+func (a *application) handlePageDataRequest(w http.ResponseWriter, r *http.Request) {
+	page, exists := a.pageForRequest(r)
+	if !exists {
+		a.handleNotFound(w, r)
+		return
+	}
+
+	page.mu.RLock()
+	response := pageDataResponse{
+		Slug:        page.Slug,
+		Title:       page.Title,
+		HeadWidgets: make([]widgetDataResponse, 0, len(page.HeadWidgets)),
+		Columns:     make([]columnDataResponse, 0, len(page.Columns)),
+	}
+
+	for _, widget := range page.HeadWidgets {
+		response.HeadWidgets = append(response.HeadWidgets, newWidgetDataResponse(widget))
+	}
+
+	for c := range page.Columns {
+		column := columnDataResponse{
+			Size:    page.Columns[c].Size,
+			Widgets: make([]widgetDataResponse, 0, len(page.Columns[c].Widgets)),
+		}
+
+		for _, widget := range page.Columns[c].Widgets {
+			column.Widgets = append(column.Widgets, newWidgetDataResponse(widget))
+		}
+
+		response.Columns = append(response.Columns, column)
+	}
+
+	encoded, err := json.Marshal(response)
+	page.mu.RUnlock()
+
+	writeEncodedDataResponse(w, encoded, err)
+}
+
+func writeEncodedDataResponse(w http.ResponseWriter, encoded []byte, err error) {
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, json.RawMessage(encoded))
+}
@@ -0,0 +1,215 @@
+package dashdashdash
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	pageEventsBufferSize        = 32
+	pageEventsKeepAliveInterval = 25 * time.Second
+	pageEventsRetryInterval     = 5 * time.Second
+)
+
+type widgetEvent struct {
+	ID   uint64 `json:"id"`
+	HTML string `json:"html"`
+}
+
+// pageEvent is sent to the subscribers of a page, either a widget event or a
+// reload event that asks the client to load the whole page again.
+type pageEvent struct {
+	name string
+	data any
+}
+
+// pageEvents sends the HTML of widgets whose rendered output changed after an
+// update to the clients subscribed to /api/pages/{page}/events.
+type pageEvents struct {
+	mu          sync.Mutex
+	subscribers map[chan pageEvent]widgetLayouts // the layout each client reported when it subscribed
+	hashes      map[uint64][sha256.Size]byte     // last sent HTML of each widget
+	closed      bool
+}
+
+func newPageEvents() *pageEvents {
+	return &pageEvents{
+		subscribers: make(map[chan pageEvent]widgetLayouts),
+		hashes:      make(map[uint64][sha256.Size]byte),
+	}
+}
+
+func (e *pageEvents) subscribe(layouts widgetLayouts) (chan pageEvent, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return nil, false
+	}
+
+	events := make(chan pageEvent, pageEventsBufferSize)
+	e.subscribers[events] = layouts
+	return events, true
+}
+
+func (e *pageEvents) unsubscribe(events chan pageEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, exists := e.subscribers[events]; exists {
+		delete(e.subscribers, events)
+		close(events)
+	}
+}
+
+func (e *pageEvents) hasSubscribers() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return len(e.subscribers) > 0
+}
+
+// subscribedLayouts returns the layouts that the page is open with. Without
+// subscribers it could be opened with any layout next.
+func (e *pageEvents) subscribedLayouts() widgetLayouts {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.subscribers) == 0 {
+		return allLayouts
+	}
+
+	var layouts widgetLayouts
+	for _, subscriberLayouts := range e.subscribers {
+		layouts |= subscriberLayouts
+	}
+
+	return layouts
+}
+
+// publishChanged renders the given widgets and sends the ones whose output
+// differs from what was last sent. Must be called with the page locked.
+func (e *pageEvents) publishChanged(updated []widget) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.subscribers) == 0 {
+		// Without anyone listening the hashes would go stale, and a client
+		// that connects later could miss a widget changing back to them.
+		clear(e.hashes)
+		return
+	}
+
+	for _, w := range updated {
+		html := w.Render()
+		hash := sha256.Sum256([]byte(html))
+		if previous, exists := e.hashes[w.GetID()]; exists && previous == hash {
+			continue
+		}
+		e.hashes[w.GetID()] = hash
+
+		e.send(pageEvent{name: "widget", data: widgetEvent{ID: w.GetID(), HTML: string(html)}})
+	}
+}
+
+// publishReload asks the clients to load the whole page again, used when
+// widgets are shown or hidden by their schedule.
+func (e *pageEvents) publishReload() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.send(pageEvent{name: "reload", data: struct{}{}})
+}
+
+// send must be called with e.mu held.
+func (e *pageEvents) send(event pageEvent) {
+	for subscriber := range e.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			// The client fell behind, drop it so that it reconnects and
+			// loads the whole page again instead of showing stale widgets.
+			delete(e.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+// close ends every open stream, used when the server shuts down since
+// streams would otherwise keep it from shutting down gracefully.
+func (e *pageEvents) close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.closed = true
+	for subscriber := range e.subscribers {
+		delete(e.subscribers, subscriber)
+		close(subscriber)
+	}
+}
+
+func (a *application) closePageEvents() {
+	for i := range a.Config.Pages {
+		a.Config.Pages[i].events.close()
+	}
+}
+
+func (a *application) handlePageEventsRequest(w http.ResponseWriter, r *http.Request) {
+	page, exists := a.pageForRequest(r)
+	if !exists {
+		a.handleNotFound(w, r)
+		return
+	}
+
+	events, ok := page.events.subscribe(layoutsForRequest(r))
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	defer page.events.unsubscribe(events)
+
+	// The stream is kept open for longer than the server's write timeout
+	controller := http.NewResponseController(w)
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil {
+		slog.Error("Could not clear write deadline of event stream", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", pageEventsRetryInterval.Milliseconds())
+
+	keepAlive := time.NewTicker(pageEventsKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		if err := controller.Flush(); err != nil {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(event.data)
+			if err != nil {
+				slog.Error("Could not encode page event", "event", event.name, "error", err)
+				continue
+			}
+
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.name, data)
+		case <-keepAlive.C:
+			w.Write([]byte(": keep-alive\n\n"))
+		}
+	}
+}
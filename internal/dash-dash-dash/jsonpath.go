@@ -0,0 +1,150 @@
+package dashdashdash
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a small subset of JSONPath that only selects a single value:
+// $.key, $.key.nested, $.list[0], $.list[-1] and $['key with spaces'], where
+// quoted keys can contain any character with \' or \" for quotes. The leading
+// $ is optional.
+type jsonPath []jsonPathSegment
+
+type jsonPathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func parseJSONPath(path string) (jsonPath, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+
+	var segments jsonPath
+
+	for len(path) > 0 {
+		switch path[0] {
+		case '.':
+			path = path[1:]
+			end := strings.IndexAny(path, ".[")
+			if end == -1 {
+				end = len(path)
+			}
+
+			if end == 0 {
+				return nil, errors.New("empty key")
+			}
+
+			segments = append(segments, jsonPathSegment{key: path[:end]})
+			path = path[end:]
+
+		case '[':
+			inner := strings.TrimLeft(path[1:], " ")
+			if len(inner) > 0 && (inner[0] == '\'' || inner[0] == '"') {
+				key, rest, err := parseJSONPathQuotedKey(inner)
+				if err != nil {
+					return nil, err
+				}
+
+				rest = strings.TrimLeft(rest, " ")
+				if !strings.HasPrefix(rest, "]") {
+					return nil, errors.New("unclosed [")
+				}
+
+				segments = append(segments, jsonPathSegment{key: key})
+				path = rest[1:]
+				continue
+			}
+
+			end := strings.IndexByte(path, ']')
+			if end == -1 {
+				return nil, errors.New("unclosed [")
+			}
+
+			inner = strings.TrimSpace(path[1:end])
+			path = path[end+1:]
+
+			index, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index %q", inner)
+			}
+
+			segments = append(segments, jsonPathSegment{index: index, isIndex: true})
+
+		default:
+			// Allow omitting the leading dot, as in "status.healthy"
+			if len(segments) == 0 {
+				path = "." + path
+				continue
+			}
+
+			return nil, fmt.Errorf("unexpected character %q", path[0])
+		}
+	}
+
+	return segments, nil
+}
+
+// parseJSONPathQuotedKey parses a key quoted with ' or " up to its closing
+// quote, where a backslash escapes the next character so that keys can contain
+// quotes. It returns the key and what follows the closing quote.
+func parseJSONPathQuotedKey(path string) (string, string, error) {
+	quote := path[0]
+	var key strings.Builder
+
+	for i := 1; i < len(path); i++ {
+		switch path[i] {
+		case quote:
+			return key.String(), path[i+1:], nil
+		case '\\':
+			if i+1 == len(path) {
+				return "", "", errors.New("unclosed quote")
+			}
+			i++
+		}
+
+		key.WriteByte(path[i])
+	}
+
+	return "", "", errors.New("unclosed quote")
+}
+
+// lookup returns the value at the path within a value decoded by
+// encoding/json, and whether it exists.
+func (p jsonPath) lookup(value any) (any, bool) {
+	for _, segment := range p {
+		if segment.isIndex {
+			list, ok := value.([]any)
+			if !ok {
+				return nil, false
+			}
+
+			index := segment.index
+			if index < 0 {
+				index += len(list)
+			}
+
+			if index < 0 || index >= len(list) {
+				return nil, false
+			}
+
+			value = list[index]
+			continue
+		}
+
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+
+		value, ok = object[segment.key]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return value, true
+}
@@ -0,0 +1,96 @@
+package dashdashdash
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const jsonPathTestDocument = `{
+	"status": {"healthy": true, "checks": [{"name": "db"}, {"name": "cache"}]},
+	"key with spaces": 1,
+	"dotted.key": 2,
+	"bracket]key": 3,
+	"it's": 4,
+	"say \"hi\"": 5,
+	"back\\slash": 6,
+	"null": null,
+	"list": [10, 20, 30]
+}`
+
+func TestJSONPathLookup(t *testing.T) {
+	var document any
+	if err := json.Unmarshal([]byte(jsonPathTestDocument), &document); err != nil {
+		t.Fatalf("decoding document: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		expected any
+		exists   bool
+	}{
+		{"$.status.healthy", true, true},
+		{"status.healthy", true, true},
+		{"$.status.checks[1].name", "cache", true},
+		{"$['key with spaces']", 1.0, true},
+		{`$["key with spaces"]`, 1.0, true},
+		{"$[ 'key with spaces' ]", 1.0, true},
+		{"$['dotted.key']", 2.0, true},
+		{"$['bracket]key']", 3.0, true},
+		{`$['it\'s']`, 4.0, true},
+		{`$["it's"]`, 4.0, true},
+		{`$["say \"hi\""]`, 5.0, true},
+		{`$['back\\slash']`, 6.0, true},
+		{"$.null", nil, true},
+		{"$.list[0]", 10.0, true},
+		{"$.list[-1]", 30.0, true},
+		{"$.list[-3]", 10.0, true},
+		{"$.list[3]", nil, false},
+		{"$.list[-4]", nil, false},
+		{"$.missing", nil, false},
+		{"$.status.missing.deeper", nil, false},
+		{"$.dotted.key", nil, false},
+		{"$.list.key", nil, false},
+		{"$.status[0]", nil, false},
+		{"$.status.healthy.value", nil, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			path, err := parseJSONPath(test.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			value, exists := path.lookup(document)
+			if exists != test.exists || !reflect.DeepEqual(value, test.expected) {
+				t.Errorf("expected (%v, %t), got (%v, %t)", test.expected, test.exists, value, exists)
+			}
+		})
+	}
+}
+
+func TestParseJSONPathErrors(t *testing.T) {
+	tests := []struct {
+		path string
+		err  string
+	}{
+		{"$.", "empty key"},
+		{"$.a..b", "empty key"},
+		{"$.list[0", "unclosed ["},
+		{"$['key'", "unclosed ["},
+		{"$['key]", "unclosed quote"},
+		{`$['key\`, "unclosed quote"},
+		{"$.list[first]", `invalid index "first"`},
+		{"$.list[0]x", `unexpected character 'x'`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			_, err := parseJSONPath(test.path)
+			if err == nil || err.Error() != test.err {
+				t.Errorf("expected error %q, got %v", test.err, err)
+			}
+		})
+	}
+}
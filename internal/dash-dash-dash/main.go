@@ -1,12 +1,15 @@
 package dashdashdash
 
 import (
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 func Main() int {
@@ -52,8 +55,28 @@ func Main() int {
 			return 1
 		}
 		fmt.Println(string(contents))
+	case cliIntentConfigSchema:
+		schema, err := json.MarshalIndent(newConfigSchema(), "", "  ")
+		if err != nil {
+			fmt.Printf("Could not generate schema: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(schema))
 	case cliIntentDiagnose:
 		return cliDiagnose(options.configPath)
+	case cliIntentPasswordHash:
+		password, err := cliReadPassword()
+		if err != nil {
+			fmt.Printf("Could not read password: %v\n", err)
+			return 1
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			fmt.Printf("Could not hash password: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(hash))
 	}
 
 	return 0
@@ -82,6 +105,7 @@ func serveApp(configPath string) error {
 	}()
 
 	onChange := func(newContents []byte) {
+		isReload := hadValidConfigOnStartup
 		if stopServer != nil {
 			slog.Info("Config file changed, reloading...")
 		}
@@ -90,6 +114,10 @@ func serveApp(configPath string) error {
 		if err != nil {
 			slog.Error("Config has errors", "error", err)
 
+			if isReload {
+				recordConfigReload(false)
+			}
+
 			if !hadValidConfigOnStartup {
 				exitOnce.Do(func() { close(exitChannel) })
 			}
@@ -101,6 +129,10 @@ func serveApp(configPath string) error {
 		if err != nil {
 			slog.Error("Failed to create application", "error", err)
 
+			if isReload {
+				recordConfigReload(false)
+			}
+
 			if !hadValidConfigOnStartup {
 				exitOnce.Do(func() { close(exitChannel) })
 			}
@@ -108,7 +140,9 @@ func serveApp(configPath string) error {
 			return
 		}
 
-		if !hadValidConfigOnStartup {
+		if isReload {
+			recordConfigReload(true)
+		} else {
 			hadValidConfigOnStartup = true
 		}
 
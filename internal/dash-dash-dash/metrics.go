@@ -0,0 +1,435 @@
+package dashdashdash
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/subtle"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type metricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Token   string `yaml:"token"`
+}
+
+var httpRequestDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// serverMetrics holds the metrics that aren't tied to a widget. It's global
+// so that the counters survive config reloads, widget metrics are read from
+// the widgets of the current config when scraped instead.
+var serverMetrics = struct {
+	mu                   sync.Mutex
+	requests             map[httpRequestMetricKey]uint64
+	requestDurations     map[string]*histogram // by route
+	configReloads        map[string]uint64     // by result
+	lastSuccessfulReload time.Time
+	feedFetches          map[string]uint64 // by URL
+	feedFetchFailures    map[string]uint64 // by URL
+}{
+	requests:          make(map[httpRequestMetricKey]uint64),
+	requestDurations:  make(map[string]*histogram),
+	configReloads:     make(map[string]uint64),
+	feedFetches:       make(map[string]uint64),
+	feedFetchFailures: make(map[string]uint64),
+}
+
+type httpRequestMetricKey struct {
+	route  string
+	method string
+	code   int
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []uint64 // cumulative counts are computed when written
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(value float64) {
+	if i, _ := slices.BinarySearch(h.buckets, value); i < len(h.buckets) {
+		h.counts[i]++
+	}
+
+	h.sum += value
+	h.count++
+}
+
+func recordHTTPRequest(route, method string, code int, duration time.Duration) {
+	serverMetrics.mu.Lock()
+	defer serverMetrics.mu.Unlock()
+
+	serverMetrics.requests[httpRequestMetricKey{route, method, code}]++
+
+	durations, exists := serverMetrics.requestDurations[route]
+	if !exists {
+		durations = newHistogram(httpRequestDurationBuckets)
+		serverMetrics.requestDurations[route] = durations
+	}
+
+	durations.observe(duration.Seconds())
+}
+
+// recordConfigReload is only called for reloads, not for the config that's
+// loaded on startup.
+func recordConfigReload(success bool) {
+	serverMetrics.mu.Lock()
+	defer serverMetrics.mu.Unlock()
+
+	serverMetrics.configReloads[ternary(success, "success", "failure")]++
+	if success {
+		serverMetrics.lastSuccessfulReload = time.Now()
+	}
+}
+
+func recordFeedFetch(url string, failed bool) {
+	url = redactURL(url)
+
+	serverMetrics.mu.Lock()
+	defer serverMetrics.mu.Unlock()
+
+	serverMetrics.feedFetches[url]++
+	if failed {
+		serverMetrics.feedFetchFailures[url]++
+	}
+}
+
+// updateWidget updates the widget and records how long it took.
+func updateWidget(ctx context.Context, w widget) {
+	start := time.Now()
+	w.update(ctx)
+	w.recordUpdate(time.Since(start))
+}
+
+// widgetUpdateStats are kept per widget, a config reload starts them over
+// since the widgets are recreated.
+type widgetUpdateStats struct {
+	Updates      uint64
+	Errors       uint64
+	DurationSum  time.Duration
+	RetriedTimes int
+}
+
+func (w *widgetBase) recordUpdate(duration time.Duration) {
+	w.updateStats.Updates++
+	w.updateStats.DurationSum += duration
+
+	if w.Error != nil {
+		w.updateStats.Errors++
+	}
+}
+
+func (w *widgetBase) getUpdateStats() widgetUpdateStats {
+	stats := w.updateStats
+	stats.RetriedTimes = w.updateRetriedTimes
+	return stats
+}
+
+// widgetMetricsCollector is implemented by widgets that expose metrics of
+// their own, the labels identify the widget and must be included in every
+// sample.
+type widgetMetricsCollector interface {
+	collectMetrics(m *metricsWriter, labels []string)
+}
+
+// metricsWriter builds a response in the Prometheus text format. Samples are
+// grouped by metric regardless of the order they're added in, as the format
+// requires.
+type metricsWriter struct {
+	families []*metricFamily
+	byName   map[string]*metricFamily
+}
+
+type metricFamily struct {
+	name    string
+	kind    string
+	help    string
+	samples bytes.Buffer
+}
+
+func newMetricsWriter() *metricsWriter {
+	return &metricsWriter{byName: make(map[string]*metricFamily)}
+}
+
+func (m *metricsWriter) family(name, kind, help string) *metricFamily {
+	if family, exists := m.byName[name]; exists {
+		return family
+	}
+
+	family := &metricFamily{name: name, kind: kind, help: help}
+	m.families = append(m.families, family)
+	m.byName[name] = family
+	return family
+}
+
+func (m *metricsWriter) counter(name, help string, value float64, labels ...string) {
+	m.family(name, "counter", help).add("", value, labels...)
+}
+
+func (m *metricsWriter) gauge(name, help string, value float64, labels ...string) {
+	m.family(name, "gauge", help).add("", value, labels...)
+}
+
+// labels are given as name/value pairs.
+func (f *metricFamily) add(suffix string, value float64, labels ...string) {
+	f.samples.WriteString(f.name)
+	f.samples.WriteString(suffix)
+
+	if len(labels) > 0 {
+		f.samples.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				f.samples.WriteByte(',')
+			}
+			f.samples.WriteString(labels[i])
+			f.samples.WriteString(`="`)
+			f.samples.WriteString(metricsLabelEscaper.Replace(labels[i+1]))
+			f.samples.WriteByte('"')
+		}
+		f.samples.WriteByte('}')
+	}
+
+	f.samples.WriteByte(' ')
+	f.samples.WriteString(formatMetricValue(value))
+	f.samples.WriteByte('\n')
+}
+
+var metricsLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatMetricValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func (h *histogram) writeTo(f *metricFamily, labels ...string) {
+	cumulative := uint64(0)
+	for i, bound := range h.buckets {
+		cumulative += h.counts[i]
+		f.add("_bucket", float64(cumulative), append(slices.Clone(labels), "le", formatMetricValue(bound))...)
+	}
+
+	f.add("_bucket", float64(h.count), append(slices.Clone(labels), "le", "+Inf")...)
+	f.add("_sum", h.sum, labels...)
+	f.add("_count", float64(h.count), labels...)
+}
+
+func (m *metricsWriter) bytes() []byte {
+	var buf bytes.Buffer
+
+	for _, family := range m.families {
+		if family.samples.Len() == 0 {
+			continue
+		}
+
+		buf.WriteString("# HELP " + family.name + " " + family.help + "\n")
+		buf.WriteString("# TYPE " + family.name + " " + family.kind + "\n")
+		buf.Write(family.samples.Bytes())
+	}
+
+	return buf.Bytes()
+}
+
+func writeServerMetrics(m *metricsWriter) {
+	serverMetrics.mu.Lock()
+	defer serverMetrics.mu.Unlock()
+
+	requests := slices.SortedFunc(maps.Keys(serverMetrics.requests), func(a, b httpRequestMetricKey) int {
+		return cmp.Or(cmp.Compare(a.route, b.route), cmp.Compare(a.method, b.method), cmp.Compare(a.code, b.code))
+	})
+
+	for _, key := range requests {
+		m.counter(
+			"dash_http_requests_total", "Number of HTTP requests by route, method and status code.",
+			float64(serverMetrics.requests[key]),
+			"route", key.route, "method", key.method, "code", strconv.Itoa(key.code),
+		)
+	}
+
+	durations := m.family("dash_http_request_duration_seconds", "histogram", "Time taken to respond to HTTP requests by route.")
+	for _, route := range slices.Sorted(maps.Keys(serverMetrics.requestDurations)) {
+		serverMetrics.requestDurations[route].writeTo(durations, "route", route)
+	}
+
+	for _, result := range []string{"success", "failure"} {
+		m.counter(
+			"dash_config_reloads_total", "Number of config reloads by result.",
+			float64(serverMetrics.configReloads[result]),
+			"result", result,
+		)
+	}
+
+	if !serverMetrics.lastSuccessfulReload.IsZero() {
+		m.gauge(
+			"dash_config_last_reload_success_timestamp_seconds", "Time of the last successful config reload.",
+			float64(serverMetrics.lastSuccessfulReload.Unix()),
+		)
+	}
+
+	for _, url := range slices.Sorted(maps.Keys(serverMetrics.feedFetches)) {
+		m.counter(
+			"dash_rss_feed_fetches_total", "Number of times an RSS feed was fetched.",
+			float64(serverMetrics.feedFetches[url]),
+			"url", url,
+		)
+		m.counter(
+			"dash_rss_feed_fetch_failures_total", "Number of times fetching an RSS feed failed.",
+			float64(serverMetrics.feedFetchFailures[url]),
+			"url", url,
+		)
+	}
+}
+
+// pageMetrics are the widget metrics of a page as of its last update. Pages
+// are locked for as long as their widgets are being updated, so the metrics
+// are taken while the page is already locked instead of waiting on it.
+type pageMetrics struct {
+	mu       sync.Mutex
+	snapshot *metricsWriter
+}
+
+// snapshotMetrics must be called with the page locked.
+func (p *page) snapshotMetrics() {
+	m := newMetricsWriter()
+	for _, w := range p.allWidgets() {
+		writeWidgetMetrics(m, p, w)
+	}
+
+	p.metrics.mu.Lock()
+	p.metrics.snapshot = m
+	p.metrics.mu.Unlock()
+}
+
+func (a *application) writeWidgetMetrics(m *metricsWriter) {
+	for i := range a.Config.Pages {
+		page := &a.Config.Pages[i]
+
+		page.metrics.mu.Lock()
+		snapshot := page.metrics.snapshot
+		page.metrics.mu.Unlock()
+
+		if snapshot != nil {
+			m.merge(snapshot)
+		}
+	}
+}
+
+// merge adds the samples of another writer, which must no longer be written to.
+func (m *metricsWriter) merge(other *metricsWriter) {
+	for _, family := range other.families {
+		m.family(family.name, family.kind, family.help).samples.Write(family.samples.Bytes())
+	}
+}
+
+func writeWidgetMetrics(m *metricsWriter, page *page, w widget) {
+	state := w.state()
+	labels := []string{
+		"page", page.Slug,
+		"widget_id", strconv.FormatUint(state.ID, 10),
+		"type", state.Type,
+		"title", state.Title,
+	}
+
+	stats := w.getUpdateStats()
+	updateDurations := m.family("dash_widget_update_duration_seconds", "summary", "Time taken to update widgets.")
+	updateDurations.add("_sum", stats.DurationSum.Seconds(), labels...)
+	updateDurations.add("_count", float64(stats.Updates), labels...)
+
+	m.counter("dash_widget_update_errors_total", "Number of widget updates that failed.", float64(stats.Errors), labels...)
+	m.gauge("dash_widget_update_retries", "Number of consecutive failed updates of a widget, capped at 5.", float64(stats.RetriedTimes), labels...)
+
+	if collector, ok := w.(widgetMetricsCollector); ok {
+		collector.collectMetrics(m, labels)
+	}
+}
+
+// metricsRouteFor returns the pattern of the route that handles the request,
+// without the method, so that path values don't create a series each.
+func metricsRouteFor(mux *http.ServeMux, r *http.Request) string {
+	_, pattern := mux.Handler(r)
+	if pattern == "" {
+		return "unmatched"
+	}
+
+	if _, path, found := strings.Cut(pattern, " "); found {
+		return path
+	}
+
+	return pattern
+}
+
+func metricsMethodFor(r *http.Request) string {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return r.Method
+	}
+
+	return "OTHER"
+}
+
+type statusRecordingResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecordingResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecordingResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, which the
+// page events stream relies on.
+func (w *statusRecordingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func metricsMiddleware(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := metricsRouteFor(mux, r)
+		method := metricsMethodFor(r)
+		recorder := &statusRecordingResponseWriter{ResponseWriter: w}
+
+		start := time.Now()
+		next.ServeHTTP(recorder, r)
+
+		recordHTTPRequest(route, method, cmp.Or(recorder.status, http.StatusOK), time.Since(start))
+	})
+}
+
+func (a *application) handleMetricsRequest(w http.ResponseWriter, r *http.Request) {
+	if token := a.Config.Server.Metrics.Token; token != "" {
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+
+	m := newMetricsWriter()
+	writeServerMetrics(m)
+	a.writeWidgetMetrics(m)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(m.bytes())
+}
@@ -0,0 +1,497 @@
+package dashdashdash
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	notificationTimeout        = 15 * time.Second
+	defaultNotifyAfterFailures = 2
+	notificationEventDown      = "down"
+	notificationEventUp        = "up"
+)
+
+type notification struct {
+	Event   string // notificationEventDown or notificationEventUp
+	Site    string
+	URL     string
+	Title   string
+	Message string
+	Time    time.Time
+}
+
+type notifier interface {
+	initialize() error
+	send(context.Context, *notification) error
+}
+
+// notifierFactories maps the type names used in the config to the notifiers
+// they create.
+var notifierFactories = map[string]func() notifier{
+	"webhook": func() notifier { return &webhookNotifier{} },
+	"ntfy":    func() notifier { return &ntfyNotifier{} },
+	"gotify":  func() notifier { return &gotifyNotifier{} },
+	"smtp":    func() notifier { return &smtpNotifier{} },
+	"command": func() notifier { return &commandNotifier{} },
+}
+
+func newNotifier(notifierType string) (notifier, error) {
+	if notifierType == "" {
+		return nil, errors.New("notifier 'type' property is empty or not specified")
+	}
+
+	factory, exists := notifierFactories[notifierType]
+	if !exists {
+		return nil, fmt.Errorf("unknown notifier type: %s", notifierType)
+	}
+
+	return factory(), nil
+}
+
+// notifiers maps the names used in the notify property of monitor sites to
+// their configured notifier.
+type notifiers map[string]notifier
+
+func (n *notifiers) UnmarshalYAML(node *yaml.Node) error {
+	var nodes map[string]yaml.Node
+
+	if err := node.Decode(&nodes); err != nil {
+		return err
+	}
+
+	*n = make(notifiers, len(nodes))
+
+	for name, node := range nodes {
+		meta := struct {
+			Type string `yaml:"type"`
+		}{}
+
+		if err := node.Decode(&meta); err != nil {
+			return err
+		}
+
+		notifier, err := newNotifier(meta.Type)
+		if err != nil {
+			return fmt.Errorf("notifier %s: line %d: %w", name, node.Line, err)
+		}
+
+		if err = node.Decode(notifier); err != nil {
+			return err
+		}
+
+		if err = notifier.initialize(); err != nil {
+			return fmt.Errorf("notifier %s: %v", name, err)
+		}
+
+		(*n)[name] = notifier
+	}
+
+	return nil
+}
+
+func dispatchNotification(targets []notifier, n *notification) {
+	for _, target := range targets {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+			defer cancel()
+
+			if err := target.send(ctx, n); err != nil {
+				slog.Error("Failed to send notification", "site", n.Site, "event", n.Event, "error", err)
+			}
+		}()
+	}
+}
+
+// monitorAlerts tracks the alert state of each monitored site. It's global so
+// that a config reload doesn't alert again for a site that's already down,
+// which is why sites are told apart by the position of their widget in the
+// config rather than by its ID.
+var monitorAlerts = struct {
+	mu     sync.Mutex
+	states map[monitorAlertKey]*monitorAlertState
+}{
+	states: make(map[monitorAlertKey]*monitorAlertState),
+}
+
+// monitorAlertKey keeps the state of sites that check the same URL from
+// different widgets separate, so that their failures aren't counted twice.
+type monitorAlertKey struct {
+	widget string
+	url    string
+	title  string
+}
+
+type monitorAlertState struct {
+	consecutiveFailures int
+	alerted             bool
+	downSince           time.Time
+}
+
+// recordMonitorCheck updates the alert state of a site after it was checked
+// and returns the notification that should be sent, if any.
+func recordMonitorCheck(widgetKey, url, title, statusText string, uptimeStatus int, notifyAfter int, notifyRecovery bool) *notification {
+	if uptimeStatus == UptimeUnknown {
+		return nil
+	}
+
+	monitorAlerts.mu.Lock()
+	defer monitorAlerts.mu.Unlock()
+
+	key := monitorAlertKey{widget: widgetKey, url: url, title: title}
+	state, exists := monitorAlerts.states[key]
+	if !exists {
+		state = &monitorAlertState{}
+		monitorAlerts.states[key] = state
+	}
+
+	now := time.Now()
+	displayURL := redactURL(url)
+
+	if uptimeStatus == UptimeDown {
+		if state.consecutiveFailures == 0 {
+			state.downSince = now
+		}
+		state.consecutiveFailures++
+
+		if state.alerted || state.consecutiveFailures < notifyAfter {
+			return nil
+		}
+
+		state.alerted = true
+		return &notification{
+			Event: notificationEventDown,
+			Site:  title,
+			URL:   displayURL,
+			Title: title + " is down",
+			Message: fmt.Sprintf(
+				"%s (%s) failed %d consecutive checks: %s",
+				title, displayURL, state.consecutiveFailures, statusText,
+			),
+			Time: now,
+		}
+	}
+
+	wasAlerted, downSince := state.alerted, state.downSince
+	state.consecutiveFailures = 0
+	state.alerted = false
+
+	if !wasAlerted || !notifyRecovery {
+		return nil
+	}
+
+	return &notification{
+		Event: notificationEventUp,
+		Site:  title,
+		URL:   displayURL,
+		Title: title + " is back up",
+		Message: fmt.Sprintf(
+			"%s (%s) is responding again after being down for %s",
+			title, displayURL, now.Sub(downSince).Round(time.Second),
+		),
+		Time: now,
+	}
+}
+
+func sendNotificationRequest(request *http.Request) error {
+	response, err := defaultHTTPClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("unexpected status code %d: %s", response.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+type webhookNotifier struct {
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+func (n *webhookNotifier) initialize() error {
+	if n.URL == "" {
+		return errors.New("url is required")
+	}
+
+	return nil
+}
+
+func (n *webhookNotifier) send(ctx context.Context, notification *notification) error {
+	body, err := json.Marshal(map[string]any{
+		"event":   notification.Event,
+		"site":    notification.Site,
+		"url":     notification.URL,
+		"title":   notification.Title,
+		"message": notification.Message,
+		"time":    notification.Time,
+	})
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	for key, value := range n.Headers {
+		request.Header.Set(key, value)
+	}
+
+	return sendNotificationRequest(request)
+}
+
+type ntfyNotifier struct {
+	URL      string `yaml:"url"`
+	Token    string `yaml:"token"`
+	Priority string `yaml:"priority"`
+}
+
+func (n *ntfyNotifier) initialize() error {
+	if n.URL == "" {
+		return errors.New("url is required and must include the topic")
+	}
+
+	return nil
+}
+
+func (n *ntfyNotifier) send(ctx context.Context, notification *notification) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, strings.NewReader(notification.Message))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Title", notification.Title)
+	request.Header.Set("Tags", ternary(notification.Event == notificationEventDown, "red_circle", "green_circle"))
+
+	if n.Priority != "" {
+		request.Header.Set("Priority", n.Priority)
+	}
+
+	if n.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+n.Token)
+	}
+
+	return sendNotificationRequest(request)
+}
+
+type gotifyNotifier struct {
+	URL      string `yaml:"url"`
+	Token    string `yaml:"token"`
+	Priority int    `yaml:"priority"`
+}
+
+func (n *gotifyNotifier) initialize() error {
+	if n.URL == "" {
+		return errors.New("url is required")
+	}
+
+	if n.Token == "" {
+		return errors.New("token is required")
+	}
+
+	if n.Priority == 0 {
+		n.Priority = 5
+	}
+
+	n.URL = strings.TrimRight(n.URL, "/")
+	return nil
+}
+
+func (n *gotifyNotifier) send(ctx context.Context, notification *notification) error {
+	body, err := json.Marshal(map[string]any{
+		"title":    notification.Title,
+		"message":  notification.Message,
+		"priority": n.Priority,
+	})
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL+"/message", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Gotify-Key", n.Token)
+
+	return sendNotificationRequest(request)
+}
+
+type smtpNotifier struct {
+	Host     string   `yaml:"host"`
+	Port     int      `yaml:"port"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+func (n *smtpNotifier) initialize() error {
+	if n.Host == "" {
+		return errors.New("host is required")
+	}
+
+	if n.From == "" {
+		return errors.New("from is required")
+	}
+
+	if len(n.To) == 0 {
+		return errors.New("to is required")
+	}
+
+	if n.Port == 0 {
+		n.Port = 587
+	}
+
+	return nil
+}
+
+// Port 465 uses implicit TLS, any other port is upgraded with STARTTLS when
+// the server supports it.
+func (n *smtpNotifier) send(ctx context.Context, notification *notification) error {
+	address := net.JoinHostPort(n.Host, strconv.Itoa(n.Port))
+	dialer := &net.Dialer{}
+
+	var conn net.Conn
+	var err error
+
+	if n.Port == 465 {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: n.Host}}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, n.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && n.Port != 465 {
+		if err := client.StartTLS(&tls.Config{ServerName: n.Host}); err != nil {
+			return err
+		}
+	}
+
+	if n.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.Username, n.Password, n.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(n.From); err != nil {
+		return err
+	}
+
+	for _, to := range n.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := writer.Write(n.message(notification)); err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// message formats the notification as an email. Line breaks are removed from
+// the header values so that a site title can't add headers of its own, and the
+// subject is encoded since it can contain any character.
+func (n *smtpNotifier) message(notification *notification) []byte {
+	headerValue := func(value string) string {
+		return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+	}
+
+	to := make([]string, len(n.To))
+	for i := range n.To {
+		to[i] = headerValue(n.To[i])
+	}
+
+	message := "From: " + headerValue(n.From) + "\r\n" +
+		"To: " + strings.Join(to, ", ") + "\r\n" +
+		"Subject: " + mime.QEncoding.Encode("utf-8", headerValue(notification.Title)) + "\r\n" +
+		"Date: " + notification.Time.Format(time.RFC1123Z) + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		notification.Message + "\r\n"
+
+	return []byte(message)
+}
+
+// commandNotifier runs a command with the details of the notification passed
+// in environment variables.
+type commandNotifier struct {
+	Command []string `yaml:"command"`
+}
+
+func (n *commandNotifier) initialize() error {
+	if len(n.Command) == 0 || n.Command[0] == "" {
+		return errors.New("command is required")
+	}
+
+	return nil
+}
+
+func (n *commandNotifier) send(ctx context.Context, notification *notification) error {
+	cmd := exec.CommandContext(ctx, n.Command[0], n.Command[1:]...)
+	cmd.Env = append(os.Environ(),
+		"DASH_EVENT="+notification.Event,
+		"DASH_SITE="+notification.Site,
+		"DASH_URL="+notification.URL,
+		"DASH_TITLE="+notification.Title,
+		"DASH_MESSAGE="+notification.Message,
+	)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
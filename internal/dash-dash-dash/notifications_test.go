@@ -0,0 +1,53 @@
+package dashdashdash
+
+import (
+	"bufio"
+	"bytes"
+	"mime"
+	"net/textproto"
+	"testing"
+	"time"
+)
+
+func TestSMTPNotifierMessageHeaders(t *testing.T) {
+	n := &smtpNotifier{
+		From: "dash <dash@example.com>\r\nBcc: from@example.com",
+		To:   []string{"admin@example.com", "family@example.com\nBcc: to@example.com"},
+	}
+
+	message := n.message(&notification{
+		Title:   "Café is down\r\nBcc: subject@example.com",
+		Message: "Café has been down since 10:00",
+		Time:    time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC),
+	})
+
+	header, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(message))).ReadMIMEHeader()
+	if err != nil {
+		t.Fatalf("reading headers: %v", err)
+	}
+
+	if bcc := header.Values("Bcc"); len(bcc) > 0 {
+		t.Errorf("expected no Bcc header, got %v", bcc)
+	}
+
+	if from := header.Get("From"); from != "dash <dash@example.com>Bcc: from@example.com" {
+		t.Errorf("unexpected From header %q", from)
+	}
+
+	if to := header.Get("To"); to != "admin@example.com, family@example.comBcc: to@example.com" {
+		t.Errorf("unexpected To header %q", to)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("decoding subject: %v", err)
+	}
+
+	if subject != "Café is downBcc: subject@example.com" {
+		t.Errorf("unexpected subject %q", subject)
+	}
+
+	if header.Get("Subject") == subject {
+		t.Error("expected the subject to be encoded")
+	}
+}
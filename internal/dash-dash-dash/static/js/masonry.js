@@ -6,6 +6,10 @@ export function setupMasonries() {
     for (let i = 0; i < masonryContainers.length; i++) {
         const container = masonryContainers[i];
 
+        // Called again after widgets are refreshed, the container is already set up
+        if (container.dataset.masonryReady) continue;
+        container.dataset.masonryReady = "true";
+
         const options = {
             minColumnWidth: container.dataset.minColumnWidth || 330,
             maxColumns: container.dataset.maxColumns || 6,
@@ -14,6 +18,17 @@ export function setupMasonries() {
         const items = Array.from(container.children);
         let previousColumnsCount = 0;
 
+        // Items may have been replaced since the last render, such as refreshed
+        // widgets, so they're collected from the columns in their original order
+        const collectItems = function() {
+            const columns = container.children;
+
+            for (let i = 0; i < items.length; i++) {
+                const item = columns[i % columns.length]?.children[Math.floor(i / columns.length)];
+                if (item) items[i] = item;
+            }
+        };
+
         const render = function() {
             const columnsCount = clamp(
                 Math.floor(container.offsetWidth / options.minColumnWidth),
@@ -24,6 +39,7 @@ export function setupMasonries() {
             if (columnsCount === previousColumnsCount) {
                 return;
             } else {
+                if (previousColumnsCount > 0) collectItems();
                 container.textContent = "";
                 previousColumnsCount = columnsCount;
             }
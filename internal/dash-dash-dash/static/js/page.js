@@ -7,9 +7,26 @@ const PAGE_CONTENT_RETRY_DELAY_MS = 1500;
 const PAGE_CONTENT_CACHE_KEY_PREFIX = 'dash-content-';
 const PAGE_CONTENT_CACHE_TTL_MS = 2 * 60 * 1000; // 2 minutes
 
+// Same breakpoint as mobile.css
+const mobileLayoutQuery = window.matchMedia('(max-width: 1190px)');
+
+const currentLayout = () => mobileLayoutQuery.matches ? 'mobile' : 'desktop';
+
+// The server leaves out the widgets that are hidden on the current layout, so
+// it's told about the layout in a cookie which is also sent with the event
+// stream
+function storeLayout() {
+    document.cookie = `layout=${currentLayout()}; path=${pageData.basePath || '/'}; max-age=31536000; SameSite=Lax`;
+}
+
+// The content differs between layouts
+function cachedContentKey(slug) {
+    return PAGE_CONTENT_CACHE_KEY_PREFIX + currentLayout() + '-' + (slug || '');
+}
+
 function getCachedContent(slug) {
     try {
-        const key = PAGE_CONTENT_CACHE_KEY_PREFIX + (slug || '');
+        const key = cachedContentKey(slug);
         const raw = localStorage.getItem(key);
         if (!raw) return null;
         const { html, fetchedAt } = JSON.parse(raw);
@@ -23,11 +40,17 @@ function getCachedContent(slug) {
 
 function setCachedContent(slug, html) {
     try {
-        const key = PAGE_CONTENT_CACHE_KEY_PREFIX + (slug || '');
+        const key = cachedContentKey(slug);
         localStorage.setItem(key, JSON.stringify({ html, fetchedAt: Date.now() }));
     } catch (_) {}
 }
 
+function removeCachedContent(slug) {
+    try {
+        localStorage.removeItem(cachedContentKey(slug));
+    } catch (_) {}
+}
+
 async function fetchPageContent(pageData, retryCount = 0) {
     const base = pageData.basePath || '';
     const url = `${base}/api/pages/${pageData.slug}/content/`;
@@ -36,6 +59,10 @@ async function fetchPageContent(pageData, retryCount = 0) {
     try {
         const response = await fetch(url, { signal: controller.signal });
         clearTimeout(timeoutId);
+        if (response.status === 401) {
+            location.href = `${base}/login`;
+            return { html: '', ok: false };
+        }
         const content = await response.text();
         if (!response.ok) {
             return { html: `<div class="widget-content padding-inline-widget" style="color: var(--color-negative);">Failed to load (${response.status}). <a href="javascript:location.reload()">Reload</a>.</div>`, ok: false };
@@ -719,6 +746,7 @@ async function setupPage() {
     const pageElement = document.getElementById("page");
     const pageContentElement = document.getElementById("page-content");
 
+    storeLayout();
     const cached = getCachedContent(pageData.slug);
     if (cached !== null) {
         await applyContentAndSetup(pageElement, pageContentElement, cached);
@@ -738,7 +766,130 @@ async function setupPage() {
     await applyContentAndSetup(pageElement, pageContentElement, result.html);
 }
 
-setupPage();
+// Widgets updated on the server are pushed over an event stream and swapped in
+// place. After a reconnect the whole page is loaded again since updates may
+// have been missed while disconnected.
+function setupPageEvents() {
+    const pageElement = document.getElementById("page");
+    const pageContentElement = document.getElementById("page-content");
+    const base = pageData.basePath || '';
+    let source = null;
+    let connectedBefore = false;
+
+    const reloadContent = async () => {
+        const result = await fetchPageContent(pageData);
+        if (result.ok) {
+            setCachedContent(pageData.slug, result.html);
+            await applyContentAndSetup(pageElement, pageContentElement, result.html);
+        }
+    };
+
+    const connect = () => {
+        source = new EventSource(`${base}/api/pages/${pageData.slug}/events`);
+
+        source.addEventListener('open', async () => {
+            if (!connectedBefore) {
+                connectedBefore = true;
+                return;
+            }
+
+            await reloadContent();
+        });
+
+        // Widgets were shown or hidden by their schedule
+        source.addEventListener('reload', reloadContent);
+
+        source.addEventListener('widget', (e) => {
+            let event;
+            try {
+                event = JSON.parse(e.data);
+            } catch (_) {
+                return;
+            }
+
+            const widgetElement = pageContentElement.querySelector(`.widget[data-widget-id="${event.id}"]`);
+            if (!widgetElement) return;
+
+            const tempDiv = document.createElement('div');
+            tempDiv.innerHTML = event.html;
+            const newWidget = tempDiv.firstElementChild;
+            if (!newWidget) return;
+
+            widgetElement.replaceWith(newWidget);
+            setupRefreshedWidget(newWidget);
+            // The cached page no longer matches what's shown
+            removeCachedContent(pageData.slug);
+        });
+    };
+
+    // Widgets hidden on the previous layout weren't sent, and the server only
+    // updates the widgets shown on the layout the stream was opened with, so
+    // the stream is opened again, which also loads the whole page again
+    mobileLayoutQuery.addEventListener('change', () => {
+        storeLayout();
+
+        if (source === null) {
+            reloadContent();
+            return;
+        }
+
+        source.close();
+        connectedBefore = true;
+        connect();
+    });
+
+    if (typeof EventSource === 'undefined') return;
+
+    connect();
+}
+
+setupPage().then(setupPageEvents);
+setupThemePicker();
+
+// The server stores the picked theme in a cookie and returns its styles, so
+// it can be applied without reloading the page
+function setupThemePicker() {
+    const markCurrentPreset = () => {
+        const presets = document.querySelectorAll('.theme-choices .theme-preset');
+        for (let i = 0; i < presets.length; i++) {
+            presets[i].classList.toggle('current', presets[i].dataset.themeKey === pageData.theme);
+        }
+    };
+
+    markCurrentPreset();
+
+    document.addEventListener('click', async function(e) {
+        const preset = e.target.closest('.theme-choices .theme-preset');
+        if (!preset || preset.dataset.themeKey === pageData.theme) return;
+
+        const key = preset.dataset.themeKey;
+        const base = pageData.basePath || '';
+
+        try {
+            const response = await fetch(`${base}/api/set-theme/${encodeURIComponent(key)}`, { method: 'POST' });
+            if (!response.ok) throw new Error(`HTTP ${response.status}`);
+
+            const theme = await response.json();
+            document.getElementById('theme-style').textContent = theme.css;
+            document.documentElement.dataset.theme = key;
+            document.documentElement.dataset.scheme = theme.scheme;
+            document.querySelector('meta[name="color-scheme"]')?.setAttribute('content', theme.scheme === 'auto' ? 'light dark' : theme.scheme);
+            document.querySelector('meta[name="theme-color"][media*="light"]')?.setAttribute('content', theme.light_background_color);
+            document.querySelector('meta[name="theme-color"][media*="dark"]')?.setAttribute('content', theme.background_color);
+            pageData.theme = key;
+        } catch (error) {
+            console.error('Failed to change theme:', error);
+            return;
+        }
+
+        const previews = document.querySelectorAll('.current-theme-preview');
+        for (let i = 0; i < previews.length; i++) {
+            previews[i].replaceChildren(preset.cloneNode(true));
+        }
+
+        markCurrentPreset();
+    });
+}
 
 // Setup functions to run after widget refresh
 function setupRefreshedWidget(widgetElement) {
@@ -856,45 +1007,75 @@ function setupRefreshedWidget(widgetElement) {
 }
 
 // Widget click-to-refresh handler
-document.addEventListener('click', async function(e) {
+document.addEventListener('click', function(e) {
     const target = e.target.closest('.widget-refresh-title');
     if (target && target.dataset.widgetId) {
         e.preventDefault();
-        const widgetId = target.dataset.widgetId;
-        const widgetElement = target.closest('.widget');
-        
-        if (!widgetElement) return;
-        
-        // Add loading state
-        widgetElement.style.opacity = '0.5';
-        widgetElement.style.pointerEvents = 'none';
+        refreshWidget(target.dataset.widgetId);
+    }
+});
+
+// Group tabs, clicking the active tab refreshes its widget
+document.addEventListener('click', function(e) {
+    const tab = e.target.closest('.widget-group-tab');
+    if (!tab) return;
+
+    if (tab.classList.contains('widget-group-tab-active')) {
+        if (tab.dataset.widgetId) refreshWidget(tab.dataset.widgetId);
+        return;
+    }
+
+    const group = tab.closest('.widget-group');
+    const tabs = group.querySelectorAll(':scope > .widget-group-tabs > .widget-group-tab');
+    const panels = group.querySelectorAll(':scope > .widget-group-panel');
+
+    for (let i = 0; i < tabs.length; i++) {
+        const isActive = tabs[i] === tab;
+        tabs[i].classList.toggle('widget-group-tab-active', isActive);
+        tabs[i].setAttribute('aria-selected', isActive ? 'true' : 'false');
+        panels[i].hidden = !isActive;
+    }
+});
+
+async function refreshWidget(widgetId) {
+    const widgetElement = document.querySelector(`.widget[data-widget-id="${widgetId}"]`);
+    
+    if (!widgetElement) return;
+    
+    // Add loading state
+    widgetElement.style.opacity = '0.5';
+    widgetElement.style.pointerEvents = 'none';
+    
+    try {
+        const base = pageData.basePath || '';
+        const response = await fetch(`${base}/api/widgets/${widgetId}/`, {
+            method: 'GET',
+            headers: { 'Accept': 'text/html' }
+        });
         
-        try {
-            const base = pageData.basePath || '';
-            const response = await fetch(`${base}/api/widgets/${widgetId}/`, {
-                method: 'GET',
-                headers: { 'Accept': 'text/html' }
-            });
+        if (response.status === 401) {
+            location.href = `${base}/login`;
+            return;
+        }
+
+        if (response.ok) {
+            const html = await response.text();
+            const tempDiv = document.createElement('div');
+            tempDiv.innerHTML = html;
+            const newWidget = tempDiv.firstElementChild;
             
-            if (response.ok) {
-                const html = await response.text();
-                const tempDiv = document.createElement('div');
-                tempDiv.innerHTML = html;
-                const newWidget = tempDiv.firstElementChild;
-                
-                if (newWidget) {
-                    widgetElement.replaceWith(newWidget);
-                    setupRefreshedWidget(newWidget);
-                }
-            } else {
-                console.error('Failed to refresh widget:', response.status);
-                widgetElement.style.opacity = '1';
-                widgetElement.style.pointerEvents = '';
+            if (newWidget) {
+                widgetElement.replaceWith(newWidget);
+                setupRefreshedWidget(newWidget);
             }
-        } catch (err) {
-            console.error('Error refreshing widget:', err);
+        } else {
+            console.error('Failed to refresh widget:', response.status);
             widgetElement.style.opacity = '1';
             widgetElement.style.pointerEvents = '';
         }
+    } catch (err) {
+        console.error('Error refreshing widget:', err);
+        widgetElement.style.opacity = '1';
+        widgetElement.style.pointerEvents = '';
     }
-});
+}
@@ -8,7 +8,7 @@ const trashIconSvg = `<svg fill="currentColor" xmlns="http://www.w3.org/2000/svg
 
 export default function(element) {
     element.swapWith(
-        Todo(element.dataset.todoId)
+        Todo(element.dataset.todoId, element.dataset.widgetId)
     )
 }
 
@@ -45,10 +45,83 @@ function saveToLocalStorage(id, data) {
     localStorage.setItem(`todo-${id}`, JSON.stringify(data));
 }
 
+function createItemId() {
+    return Date.now().toString(36) + Math.random().toString(36).slice(2, 10);
+}
+
+// Keeps the list in sync with the server when it has storage enabled. Requests
+// are sent one at a time so that they're applied in the order they were made.
+// If one fails, the list is marked as unsynced and the next change (or page
+// load) replaces the server's copy with the local one, so the last browser to
+// write wins and changes made elsewhere in the meantime are lost. Otherwise the
+// server's copy wins on load.
+function TodoSync(todoId, widgetId, getItems) {
+    const endpoint = `${pageData.basePath || ''}/api/widgets/${widgetId}/items`;
+    const unsyncedKey = `todo-${todoId}-unsynced`;
+    const syncedKey = `todo-${todoId}-synced`;
+    let queue = Promise.resolve();
+
+    const isUnsynced = () => localStorage.getItem(unsyncedKey) !== null;
+    const markUnsynced = () => localStorage.setItem(unsyncedKey, "1");
+    const hasSynced = () => localStorage.getItem(syncedKey) !== null;
+    const markSynced = () => localStorage.setItem(syncedKey, "1");
+
+    const request = async (method, path = "", body) => {
+        const response = await fetch(endpoint + path, {
+            method,
+            headers: body === undefined ? {} : { "Content-Type": "application/json" },
+            body: body === undefined ? undefined : JSON.stringify(body),
+        });
+
+        if (!response.ok) throw new Error(`${method} ${endpoint + path} failed with status ${response.status}`);
+        return response.status === 204 ? null : response.json();
+    };
+
+    const resync = async () => {
+        const items = await request("PUT", "", getItems());
+        localStorage.removeItem(unsyncedKey);
+        markSynced();
+        return items;
+    };
+
+    const send = (method, path, body) => {
+        queue = queue
+            .then(() => isUnsynced() ? resync() : request(method, path, body))
+            .catch(markUnsynced);
+    };
+
+    return {
+        load: () => {
+            const loaded = queue
+                .then(async () => {
+                    if (isUnsynced()) return resync();
+
+                    // Items that were only kept locally before storage was
+                    // enabled are sent to the server once, later an empty list
+                    // on the server means the items were removed elsewhere
+                    const items = await request("GET");
+                    if (!hasSynced() && items.length === 0 && getItems().length > 0) return resync();
+
+                    markSynced();
+                    return items;
+                })
+                .catch(() => null);
+
+            queue = loaded;
+            return loaded;
+        },
+        add: (data, prepend) => send("POST", prepend ? "?position=first" : "", data),
+        update: (data) => send("PATCH", `/${encodeURIComponent(data.id)}`, { text: data.text, checked: data.checked }),
+        remove: (data) => send("DELETE", `/${encodeURIComponent(data.id)}`),
+        reorder: (ids) => send("PUT", "/order", ids),
+    };
+}
+
 function Item(unserialize = {}, onUpdate, onDelete, onEscape, onDragStart) {
     let item, input, inputArea;
 
     const serializable = {
+        id: unserialize.id || createItemId(),
         text: unserialize.text || "",
         checked: unserialize.checked || false
     };
@@ -60,7 +133,7 @@ function Item(unserialize = {}, onUpdate, onDelete, onEscape, onDragStart) {
             .attrs({ type: "checkbox" })
             .on("change", (e) => {
                 serializable.checked = e.target.checked;
-                onUpdate();
+                onUpdate(serializable, true);
             })
             .tap(self => self.checked = serializable.checked),
 
@@ -80,7 +153,7 @@ function Item(unserialize = {}, onUpdate, onDelete, onEscape, onDragStart) {
             })
             .on("input", () => {
                 serializable.text = inputArea.value;
-                onUpdate();
+                onUpdate(serializable);
             })
         ).classes("min-width-0", "grow").append(
             elem()
@@ -101,7 +174,7 @@ function Item(unserialize = {}, onUpdate, onDelete, onEscape, onDragStart) {
     });
 }
 
-function Todo(id) {
+function Todo(id, widgetId) {
     let items, input, inputArea, inputContainer, lastAddedItem;
     let queuedForRemoval = 0;
     let reorderable;
@@ -113,25 +186,44 @@ function Todo(id) {
         reorderable.component.onDragStart(event, element);
     };
 
+    const serializeItems = () => items.children.map(item => item.component.serialize());
+    const sync = widgetId !== undefined ? TodoSync(id, widgetId, serializeItems) : null;
+
     const saveItems = () => {
         if (isDragging) return;
 
-        saveToLocalStorage(
-            id, items.children.map(item => item.component.serialize())
-        );
+        saveToLocalStorage(id, serializeItems());
+    };
+
+    const onItemRepositioned = () => {
+        saveItems();
+        sync?.reorder(serializeItems().map(data => data.id));
+    };
+
+    const changedItems = new Set();
+    const flushItemChanges = () => {
+        saveItems();
+        changedItems.forEach(data => sync?.update(data));
+        changedItems.clear();
     };
 
-    const onItemRepositioned = () => saveItems();
-    const debouncedOnItemUpdate = throttledDebounce(saveItems, 10, 1000);
+    const debouncedFlushItemChanges = throttledDebounce(flushItemChanges, 10, 1000);
+    const onItemUpdate = (data, immediate = false) => {
+        changedItems.add(data);
+        immediate ? flushItemChanges() : debouncedFlushItemChanges();
+    };
 
     const onItemDelete = (item) => {
         if (lastAddedItem === item) lastAddedItem = null;
         const height = item.clientHeight;
         queuedForRemoval++;
         item.animate(itemAnim(height, false), () => {
+            const data = item.component.serialize();
+            changedItems.delete(data);
             item.remove();
             queuedForRemoval--;
             saveItems();
+            sync?.remove(data);
         });
 
         if (items.children.length - queuedForRemoval === 0)
@@ -140,7 +232,7 @@ function Todo(id) {
 
     const newItem = (data) => Item(
         data,
-        debouncedOnItemUpdate,
+        onItemUpdate,
         onItemDelete,
         () => inputArea.focus(),
         onDragStart
@@ -152,6 +244,7 @@ function Todo(id) {
 
         prepend ? items.prepend(item) : items.append(item);
         saveItems();
+        sync?.add(item.component.serialize(), prepend);
         const height = item.clientHeight;
         item.animate(itemAnim(height));
 
@@ -185,6 +278,19 @@ function Todo(id) {
             ...loadFromLocalStorage(id).map(data => newItem(data))
         );
 
+    // Render the local copy right away and swap in the server's once it arrives
+    sync?.load().then(serverItems => {
+        if (serverItems === null) return;
+
+        const hadItems = items.children.length > 0;
+        items.replaceChildren(...serverItems.map(data => newItem(data)));
+        lastAddedItem = null;
+        saveItems();
+
+        if (hadItems !== serverItems.length > 0)
+            inputContainer.animate(inputMarginAnim(serverItems.length > 0));
+    });
+
     return fragment().append(
         inputContainer = elem()
             .classes("todo-input", "flex", "gap-10", "items-center")
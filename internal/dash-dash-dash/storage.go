@@ -0,0 +1,77 @@
+package dashdashdash
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// dataStorage persists small JSON documents under server.data-path. Callers
+// are responsible for serializing access to the documents they own.
+type dataStorage struct {
+	path string
+}
+
+func newDataStorage(path string) (*dataStorage, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return nil, fmt.Errorf("creating data directory: %v", err)
+	}
+
+	return &dataStorage{path: path}, nil
+}
+
+// load decodes the named document into v, returning false if it doesn't
+// exist yet.
+func (s *dataStorage) load(name string, v any) (bool, error) {
+	contents, err := os.ReadFile(filepath.Join(s.path, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := json.Unmarshal(contents, v); err != nil {
+		return false, fmt.Errorf("decoding %s: %v", name, err)
+	}
+
+	return true, nil
+}
+
+// save writes the named document atomically so that a crash mid-write can't
+// leave behind a truncated file.
+func (s *dataStorage) save(name string, v any) error {
+	contents, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding %s: %v", name, err)
+	}
+
+	tmp, err := os.CreateTemp(s.path, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(s.path, name))
+}
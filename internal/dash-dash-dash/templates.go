@@ -5,6 +5,7 @@ import (
 	"math"
 	"net/url"
 	"strconv"
+	"time"
 
 	"golang.org/x/text/language"
 	"golang.org/x/text/message"
@@ -28,6 +29,15 @@ var globalTemplateFunctions = template.FuncMap{
 	},
 	"dynamicRelativeTimeAttrs": dynamicRelativeTimeAttrs,
 	"faviconURLFor": faviconURLFor,
+	// Containers render their widgets without knowing the request, so these
+	// are shown on any layout unless the layouts are passed
+	"visibleWidgets": func(list []widget, layouts ...widgetLayouts) []widget {
+		if len(layouts) == 0 {
+			return visibleWidgetsAt(list, time.Now(), allLayouts)
+		}
+
+		return visibleWidgetsAt(list, time.Now(), layouts[0])
+	},
 }
 
 func mustParseTemplate(primary string, dependencies ...string) *template.Template {
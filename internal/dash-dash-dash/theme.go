@@ -1,12 +1,28 @@
 package dashdashdash
 
 import (
+	"errors"
 	"fmt"
 	"html/template"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 var themeStyleTemplate = mustParseTemplate("theme-style.gotmpl")
 
+// Preset keys end up in the theme cookie and the /api/set-theme/{key} path
+var themePresetKeyPattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+const (
+	defaultThemeKey     = "default"
+	themeCookieName     = "theme"
+	themeCookieLifetime = 2 * 365 * 24 * time.Hour
+)
+
 type themeProperties struct {
 	BackgroundColor          *hslColorField `yaml:"background-color"`
 	PrimaryColor             *hslColorField `yaml:"primary-color"`
@@ -16,23 +32,239 @@ type themeProperties struct {
 	ContrastMultiplier       float32        `yaml:"contrast-multiplier"`
 	TextSaturationMultiplier float32        `yaml:"text-saturation-multiplier"`
 
+	// When both are set the theme follows the color scheme of the OS, the
+	// options above apply to both schemes unless overridden.
+	LightScheme *themeProperties `yaml:"light-scheme"`
+	DarkScheme  *themeProperties `yaml:"dark-scheme"`
+
 	Key                  string       `yaml:"-"`
 	CSS                  template.CSS `yaml:"-"`
-	BackgroundColorAsHex  string       `yaml:"-"`
+	BackgroundColorAsHex string       `yaml:"-"`
+	// Same as BackgroundColorAsHex unless the theme follows the OS, in which
+	// case BackgroundColorAsHex is that of the dark scheme.
+	LightBackgroundColorAsHex string `yaml:"-"`
 }
 
+const defaultThemeBackgroundColorAsHex = "#151519"
+
 func (t *themeProperties) init() error {
+	if (t.LightScheme == nil) != (t.DarkScheme == nil) {
+		return errors.New("light-scheme and dark-scheme must be set together")
+	}
+
+	if t.IsAuto() {
+		if t.Light {
+			return errors.New("light can't be set along with light-scheme and dark-scheme")
+		}
+
+		if t.LightScheme.IsAuto() || t.DarkScheme.IsAuto() {
+			return errors.New("light-scheme and dark-scheme can't be nested")
+		}
+
+		if t.LightScheme.BackgroundColor == nil {
+			return errors.New("light-scheme requires a background-color")
+		}
+	}
+
 	css, err := executeTemplateToString(themeStyleTemplate, t)
 	if err != nil {
 		return fmt.Errorf("compiling theme style: %v", err)
 	}
 	t.CSS = template.CSS(whitespaceAtBeginningOfLinePattern.ReplaceAllString(css, ""))
 
-	if t.BackgroundColor != nil {
-		t.BackgroundColorAsHex = t.BackgroundColor.ToHex()
+	background := t.BackgroundColor
+	if t.IsAuto() && t.DarkScheme.BackgroundColor != nil {
+		background = t.DarkScheme.BackgroundColor
+	}
+
+	if background != nil {
+		t.BackgroundColorAsHex = background.ToHex()
 	} else {
-		t.BackgroundColorAsHex = "#151519"
+		t.BackgroundColorAsHex = defaultThemeBackgroundColorAsHex
+	}
+
+	t.LightBackgroundColorAsHex = t.BackgroundColorAsHex
+	if t.IsAuto() {
+		t.LightBackgroundColorAsHex = t.LightScheme.BackgroundColor.ToHex()
 	}
 
 	return nil
 }
+
+// clone returns a copy of the theme that can be decoded into without changing
+// the original, since the yaml package reuses the pointers it finds.
+func (t *themeProperties) clone() *themeProperties {
+	c := *t
+
+	for _, color := range []**hslColorField{&c.BackgroundColor, &c.PrimaryColor, &c.PositiveColor, &c.NegativeColor} {
+		if *color != nil {
+			copied := **color
+			*color = &copied
+		}
+	}
+
+	if c.LightScheme != nil {
+		c.LightScheme = c.LightScheme.clone()
+	}
+
+	if c.DarkScheme != nil {
+		c.DarkScheme = c.DarkScheme.clone()
+	}
+
+	return &c
+}
+
+// pageTheme is the theme of a page, which only overrides the options of the
+// main theme that it sets. The YAML is kept since the main theme isn't known
+// yet when the page is decoded.
+type pageTheme struct {
+	*themeProperties
+	node yaml.Node
+}
+
+func (t *pageTheme) UnmarshalYAML(node *yaml.Node) error {
+	// Decoded on its own as well so that errors are reported with the page
+	if err := node.Decode(&themeProperties{}); err != nil {
+		return err
+	}
+
+	t.node = *node
+	return nil
+}
+
+// applyTo sets the options of the page's theme on top of a copy of the given
+// theme. When the page changes the background or sets light without setting
+// light-scheme and dark-scheme itself, the schemes of the given theme aren't
+// kept, since they would override its choice.
+func (t *pageTheme) applyTo(base *themeProperties) error {
+	theme := base.clone()
+
+	var keys map[string]yaml.Node
+	if err := t.node.Decode(&keys); err != nil {
+		return err
+	}
+
+	_, setsLight := keys["light"]
+	_, setsBackground := keys["background-color"]
+	_, setsLightScheme := keys["light-scheme"]
+	_, setsDarkScheme := keys["dark-scheme"]
+
+	if (setsLight || setsBackground) && !setsLightScheme && !setsDarkScheme {
+		theme.LightScheme, theme.DarkScheme = nil, nil
+	}
+
+	if err := t.node.Decode(theme); err != nil {
+		return err
+	}
+
+	t.themeProperties = theme
+	return nil
+}
+
+// IsAuto reports whether the theme follows the color scheme of the OS.
+func (t *themeProperties) IsAuto() bool {
+	return t.LightScheme != nil && t.DarkScheme != nil
+}
+
+// Scheme returns the value of the data-scheme attribute of the document.
+func (t *themeProperties) Scheme() string {
+	if t.IsAuto() {
+		return "auto"
+	}
+
+	return ternary(t.Light, "light", "dark")
+}
+
+// initThemes compiles the default theme and the presets, in the order they're
+// shown in the theme picker.
+func (a *application) initThemes() error {
+	theme := &a.Config.Theme
+
+	theme.Key = defaultThemeKey
+	if err := theme.init(); err != nil {
+		return fmt.Errorf("initializing default theme: %v", err)
+	}
+	a.themes = []*themeProperties{&theme.themeProperties}
+
+	for key, preset := range theme.Presets.Items() {
+		if !themePresetKeyPattern.MatchString(key) {
+			return fmt.Errorf("theme preset name %q can only contain lowercase letters, numbers and dashes", key)
+		}
+
+		if key == defaultThemeKey {
+			return fmt.Errorf("theme preset name %s is reserved for the main theme", defaultThemeKey)
+		}
+
+		if preset == nil {
+			return fmt.Errorf("theme preset %s is empty", key)
+		}
+
+		preset.Key = key
+		if err := preset.init(); err != nil {
+			return fmt.Errorf("initializing theme preset %s: %v", key, err)
+		}
+		a.themes = append(a.themes, preset)
+	}
+
+	return nil
+}
+
+// Themes returns the themes that can be picked, the default one first.
+func (a *application) Themes() []*themeProperties {
+	return a.themes
+}
+
+func (a *application) themeByKey(key string) (*themeProperties, bool) {
+	for _, theme := range a.themes {
+		if theme.Key == key {
+			return theme, true
+		}
+	}
+
+	return nil, false
+}
+
+// themeForRequest returns the theme picked by the user, falling back to the
+// default one when the cookie is missing or refers to a removed preset.
+func (a *application) themeForRequest(r *http.Request) *themeProperties {
+	if cookie, err := r.Cookie(themeCookieName); err == nil {
+		if theme, exists := a.themeByKey(cookie.Value); exists {
+			return theme
+		}
+	}
+
+	return a.themes[0]
+}
+
+type setThemeResponse struct {
+	CSS                  template.CSS `json:"css"`
+	Scheme               string       `json:"scheme"`
+	BackgroundColor      string       `json:"background_color"`
+	LightBackgroundColor string       `json:"light_background_color"`
+}
+
+func (a *application) handleSetThemeRequest(w http.ResponseWriter, r *http.Request) {
+	theme, exists := a.themeByKey(r.PathValue("key"))
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Theme not found"))
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     themeCookieName,
+		Value:    theme.Key,
+		Path:     a.cookiePath(),
+		MaxAge:   int(themeCookieLifetime.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(a.Config.Server.BaseURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	writeJSONResponse(w, http.StatusOK, setThemeResponse{
+		CSS:                  theme.CSS,
+		Scheme:               theme.Scheme(),
+		BackgroundColor:      theme.BackgroundColorAsHex,
+		LightBackgroundColor: theme.LightBackgroundColorAsHex,
+	})
+}
@@ -0,0 +1,43 @@
+package dashdashdash
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestInitThemesPresetKeys(t *testing.T) {
+	tests := []struct {
+		key string
+		err string
+	}{
+		{key: "dark-blue-2"},
+		{key: "default", err: "theme preset name default is reserved for the main theme"},
+		{key: "Dark", err: `theme preset name "Dark" can only contain lowercase letters, numbers and dashes`},
+		{key: "dark blue", err: `theme preset name "dark blue" can only contain lowercase letters, numbers and dashes`},
+		{key: "dark/blue", err: `theme preset name "dark/blue" can only contain lowercase letters, numbers and dashes`},
+		{key: "dark;blue", err: `theme preset name "dark;blue" can only contain lowercase letters, numbers and dashes`},
+		{key: "''", err: `theme preset name "" can only contain lowercase letters, numbers and dashes`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			a := &application{}
+			if err := yaml.Unmarshal([]byte("theme:\n  presets:\n    "+test.key+":\n      light: true\n"), &a.Config); err != nil {
+				t.Fatalf("parsing config: %v", err)
+			}
+
+			err := a.initThemes()
+			if test.err == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != test.err {
+				t.Errorf("expected error %q, got %v", test.err, err)
+			}
+		})
+	}
+}
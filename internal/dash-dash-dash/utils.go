@@ -2,6 +2,8 @@ package dashdashdash
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"math"
@@ -9,6 +11,7 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -82,6 +85,30 @@ func executeTemplateToString(t *template.Template, data any) (string, error) {
 	return b.String(), nil
 }
 
+const maxJSONRequestBodySize = 1 << 20
+
+var errInvalidRequestBody = errors.New("invalid request body")
+
+func writeJSONResponse(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func decodeJSONRequestBody[T any](w http.ResponseWriter, r *http.Request) (T, error) {
+	var result T
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxJSONRequestBodySize))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&result); err != nil {
+		return result, fmt.Errorf("%w: %v", errInvalidRequestBody, err)
+	}
+
+	return result, nil
+}
+
 func ternary[T any](condition bool, a, b T) T {
 	if condition {
 		return a
@@ -91,6 +118,21 @@ func ternary[T any](condition bool, a, b T) T {
 }
 
 func hslToHex(h, s, l float64) string {
+	r, g, b := hslToRGB(h, s, l)
+	return rgbToHex(r, g, b)
+}
+
+func rgbToHex(r, g, b float64) string {
+	ir := int(math.Max(0, math.Min(255, math.Round(r))))
+	ig := int(math.Max(0, math.Min(255, math.Round(g))))
+	ib := int(math.Max(0, math.Min(255, math.Round(b))))
+
+	return fmt.Sprintf("#%02x%02x%02x", ir, ig, ib)
+}
+
+// hslToRGB converts a hue in degrees and a saturation and lightness in
+// percent to red, green and blue between 0 and 255.
+func hslToRGB(h, s, l float64) (float64, float64, float64) {
 	s /= 100.0
 	l /= 100.0
 
@@ -134,13 +176,82 @@ func hslToHex(h, s, l float64) string {
 		b = hueToRgb(p, q, h-1.0/3.0)
 	}
 
-	ir := int(math.Round(r * 255.0))
-	ig := int(math.Round(g * 255.0))
-	ib := int(math.Round(b * 255.0))
+	return r * 255.0, g * 255.0, b * 255.0
+}
 
-	ir = int(math.Max(0, math.Min(255, float64(ir))))
-	ig = int(math.Max(0, math.Min(255, float64(ig))))
-	ib = int(math.Max(0, math.Min(255, float64(ib))))
+// rgbToHSL is the inverse of hslToRGB.
+func rgbToHSL(r, g, b float64) (float64, float64, float64) {
+	r /= 255.0
+	g /= 255.0
+	b /= 255.0
 
-	return fmt.Sprintf("#%02x%02x%02x", ir, ig, ib)
+	high := math.Max(r, math.Max(g, b))
+	low := math.Min(r, math.Min(g, b))
+	l := (high + low) / 2
+
+	if high == low {
+		return 0, 0, l * 100
+	}
+
+	d := high - low
+	s := d / (1 - math.Abs(2*l-1))
+
+	var h float64
+	switch high {
+	case r:
+		h = math.Mod((g-b)/d+6, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+
+	return h * 60, s * 100, l * 100
+}
+
+// hexToRGB parses #rgb and #rrggbb colors into red, green and blue between 0
+// and 255.
+func hexToRGB(hex string) (float64, float64, float64, error) {
+	digits, found := strings.CutPrefix(hex, "#")
+	if !found || (len(digits) != 3 && len(digits) != 6) {
+		return 0, 0, 0, errors.New("expected #rgb or #rrggbb")
+	}
+
+	if len(digits) == 3 {
+		digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+	}
+
+	value, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil {
+		return 0, 0, 0, errors.New("expected hexadecimal digits")
+	}
+
+	return float64(value >> 16 & 0xff), float64(value >> 8 & 0xff), float64(value & 0xff), nil
+}
+
+// oklchToRGB converts a lightness between 0 and 1, a chroma and a hue in
+// degrees to red, green and blue between 0 and 255. Colors outside of sRGB
+// are clipped.
+func oklchToRGB(lightness, chroma, hue float64) (float64, float64, float64) {
+	hue = hue * math.Pi / 180
+	a := chroma * math.Cos(hue)
+	b := chroma * math.Sin(hue)
+
+	l := math.Pow(lightness+0.3963377774*a+0.2158037573*b, 3)
+	m := math.Pow(lightness-0.1055613458*a-0.0638541728*b, 3)
+	s := math.Pow(lightness-0.0894841775*a-1.2914855480*b, 3)
+
+	toSRGB := func(linear float64) float64 {
+		if linear <= 0.0031308 {
+			linear *= 12.92
+		} else {
+			linear = 1.055*math.Pow(linear, 1/2.4) - 0.055
+		}
+
+		return math.Max(0, math.Min(1, linear)) * 255
+	}
+
+	return toSRGB(4.0767416621*l - 3.3077115913*m + 0.2309699292*s),
+		toSRGB(-1.2684380046*l + 2.6097574011*m - 0.3413193965*s),
+		toSRGB(-0.0041960863*l - 0.7034186147*m + 1.7076147010*s)
 }
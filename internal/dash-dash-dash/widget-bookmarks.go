@@ -75,3 +75,36 @@ func (widget *bookmarksWidget) initialize() error {
 func (widget *bookmarksWidget) Render() template.HTML {
 	return widget.cachedHTML
 }
+
+func (widget *bookmarksWidget) data() any {
+	type linkData struct {
+		Title       string `json:"title"`
+		URL         string `json:"url"`
+		Description string `json:"description"`
+	}
+
+	type groupData struct {
+		Title string     `json:"title"`
+		Links []linkData `json:"links"`
+	}
+
+	groups := make([]groupData, 0, len(widget.Groups))
+	for g := range widget.Groups {
+		group := groupData{
+			Title: widget.Groups[g].Title,
+			Links: make([]linkData, 0, len(widget.Groups[g].Links)),
+		}
+
+		for _, link := range widget.Groups[g].Links {
+			group.Links = append(group.Links, linkData{
+				Title:       link.Title,
+				URL:         link.URL,
+				Description: link.Description,
+			})
+		}
+
+		groups = append(groups, group)
+	}
+
+	return map[string]any{"groups": groups}
+}
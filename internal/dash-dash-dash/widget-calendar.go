@@ -43,3 +43,7 @@ func (widget *calendarWidget) initialize() error {
 func (widget *calendarWidget) Render() template.HTML {
 	return widget.cachedHTML
 }
+
+func (widget *calendarWidget) data() any {
+	return map[string]any{"first_day_of_week": widget.FirstDayOfWeek}
+}
@@ -46,3 +46,20 @@ func (widget *clockWidget) initialize() error {
 func (widget *clockWidget) Render() template.HTML {
 	return widget.cachedHTML
 }
+
+func (widget *clockWidget) data() any {
+	type timezoneData struct {
+		Timezone string `json:"timezone"`
+		Label    string `json:"label"`
+	}
+
+	timezones := make([]timezoneData, 0, len(widget.Timezones))
+	for _, timezone := range widget.Timezones {
+		timezones = append(timezones, timezoneData{Timezone: timezone.Timezone, Label: timezone.Label})
+	}
+
+	return map[string]any{
+		"hour_format": widget.HourFormat,
+		"timezones":   timezones,
+	}
+}
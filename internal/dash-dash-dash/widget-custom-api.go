@@ -0,0 +1,309 @@
+package dashdashdash
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var customAPIWidgetTemplate = mustParseTemplate("custom-api.html", "widget-base.html")
+
+const defaultCustomAPICacheDuration = time.Hour
+
+// customAPITemplateFunctions are available in the user supplied template in
+// addition to the global ones.
+var customAPITemplateFunctions = template.FuncMap{
+	"relativeTime": dynamicRelativeTimeAttrs,
+	"formatDecimal": func(places int, value float64) string {
+		return intl.Sprintf("%.*f", places, value)
+	},
+	"formatBytes": formatBytes,
+	"add":         func(a, b float64) float64 { return a + b },
+	"sub":         func(a, b float64) float64 { return a - b },
+	"mul":         func(a, b float64) float64 { return a * b },
+	"div": func(a, b float64) float64 {
+		if b == 0 {
+			return 0
+		}
+		return a / b
+	},
+}
+
+type customAPIWidget struct {
+	widgetBase    `yaml:",inline"`
+	URL           string            `yaml:"url"`
+	Method        string            `yaml:"method"`
+	Headers       map[string]string `yaml:"headers"`
+	Body          any               `yaml:"body"`
+	AllowInsecure bool              `yaml:"allow-insecure"`
+	BasicAuth     struct {
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+	} `yaml:"basic-auth"`
+	Template     string             `yaml:"template"`
+	CompiledHTML template.HTML      `yaml:"-"`
+	template     *template.Template `yaml:"-"`
+	body         []byte             `yaml:"-"`
+	client       *http.Client       `yaml:"-"`
+}
+
+func (widget *customAPIWidget) IsRefreshable() bool {
+	return true
+}
+
+func (widget *customAPIWidget) initialize() error {
+	widget.withTitle("Custom API").withCacheDuration(defaultCustomAPICacheDuration)
+
+	if widget.URL == "" {
+		return errors.New("url is required")
+	}
+
+	if strings.TrimSpace(widget.Template) == "" {
+		return errors.New("template is required")
+	}
+
+	widget.Method = strings.ToUpper(widget.Method)
+	if widget.Method == "" {
+		widget.Method = http.MethodGet
+	}
+
+	// Strings are sent as they are, anything else is encoded as JSON
+	switch body := widget.Body.(type) {
+	case nil:
+	case string:
+		widget.body = []byte(body)
+	default:
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding body: %v", err)
+		}
+		widget.body = encoded
+	}
+
+	compiled, err := template.New("template").
+		Funcs(globalTemplateFunctions).
+		Funcs(customAPITemplateFunctions).
+		Parse(widget.Template)
+	if err != nil {
+		return fmt.Errorf("parsing template: %v", err)
+	}
+	widget.template = compiled
+
+	widget.client = defaultHTTPClient
+	if widget.AllowInsecure {
+		widget.client = &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+				Proxy:           http.ProxyFromEnvironment,
+			},
+			Timeout: defaultClientTimeout,
+		}
+	}
+
+	return nil
+}
+
+func (widget *customAPIWidget) update(ctx context.Context) {
+	html, err := widget.fetchAndRender(ctx)
+	if !widget.canContinueUpdateAfterHandlingErr(err) {
+		return
+	}
+
+	widget.CompiledHTML = html
+}
+
+func (widget *customAPIWidget) fetchAndRender(ctx context.Context) (template.HTML, error) {
+	var body io.Reader
+	if widget.body != nil {
+		body = bytes.NewReader(widget.body)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, widget.Method, widget.URL, body)
+	if err != nil {
+		return "", err
+	}
+
+	request.Header.Set("User-Agent", userAgentString)
+	request.Header.Set("Accept", "application/json")
+	if widget.body != nil && json.Valid(widget.body) {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	if widget.BasicAuth.Username != "" {
+		request.SetBasicAuth(widget.BasicAuth.Username, widget.BasicAuth.Password)
+	}
+
+	for key, value := range widget.Headers {
+		request.Header.Set(key, value)
+	}
+
+	// Set after the headers so that the Host header isn't lost
+	if host := request.Header.Get("Host"); host != "" {
+		request.Host = host
+	}
+
+	response, err := decodeJsonFromRequest[any](widget.client, request)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := widget.template.Execute(&buf, customAPITemplateData{JSON: customAPIValue{response}}); err != nil {
+		return "", fmt.Errorf("executing template: %v", err)
+	}
+
+	return template.HTML(buf.String()), nil
+}
+
+func (widget *customAPIWidget) Render() template.HTML {
+	return widget.renderTemplate(widget, customAPIWidgetTemplate)
+}
+
+// The response isn't included since it may contain more than the template
+// shows.
+func (widget *customAPIWidget) data() any {
+	return map[string]any{"html": widget.CompiledHTML}
+}
+
+type customAPITemplateData struct {
+	JSON customAPIValue
+}
+
+// customAPIValue wraps a value decoded from the response so that templates can
+// select values within it with a JSON path, such as {{ .JSON.Int "stats.total" }}.
+// An empty path selects the value itself. Missing values and values of the
+// wrong type result in the zero value.
+type customAPIValue struct {
+	value any
+}
+
+func (v customAPIValue) lookup(path string) (any, bool) {
+	parsed, err := parseJSONPath(path)
+	if err != nil {
+		return nil, false
+	}
+
+	return parsed.lookup(v.value)
+}
+
+func (v customAPIValue) Exists(path string) bool {
+	_, exists := v.lookup(path)
+	return exists
+}
+
+func (v customAPIValue) Get(path string) customAPIValue {
+	value, _ := v.lookup(path)
+	return customAPIValue{value}
+}
+
+func (v customAPIValue) String(path string) string {
+	value, _ := v.lookup(path)
+
+	switch value := value.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	case nil:
+		return ""
+	}
+
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+func (v customAPIValue) Float(path string) float64 {
+	value, _ := v.lookup(path)
+
+	switch value := value.(type) {
+	case float64:
+		return value
+	case string:
+		parsed, _ := strconv.ParseFloat(value, 64)
+		return parsed
+	}
+
+	return 0
+}
+
+func (v customAPIValue) Int(path string) int {
+	return int(v.Float(path))
+}
+
+func (v customAPIValue) Bool(path string) bool {
+	value, _ := v.lookup(path)
+
+	switch value := value.(type) {
+	case bool:
+		return value
+	case string:
+		parsed, _ := strconv.ParseBool(value)
+		return parsed
+	}
+
+	return false
+}
+
+func (v customAPIValue) Array(path string) []customAPIValue {
+	value, _ := v.lookup(path)
+
+	list, ok := value.([]any)
+	if !ok {
+		return nil
+	}
+
+	values := make([]customAPIValue, len(list))
+	for i := range list {
+		values[i] = customAPIValue{list[i]}
+	}
+
+	return values
+}
+
+// Time parses RFC 3339 strings and Unix timestamps in seconds.
+func (v customAPIValue) Time(path string) time.Time {
+	value, _ := v.lookup(path)
+
+	switch value := value.(type) {
+	case float64:
+		return time.Unix(int64(value), 0)
+	case string:
+		if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+			return parsed
+		}
+
+		if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return time.Unix(seconds, 0)
+		}
+	}
+
+	return time.Time{}
+}
+
+func formatBytes(value float64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB", "PB"}
+
+	unit := 0
+	for math.Abs(value) >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+
+	if unit == 0 {
+		return strconv.FormatFloat(value, 'f', 0, 64) + " " + units[unit]
+	}
+
+	return strconv.FormatFloat(value, 'f', 1, 64) + " " + units[unit]
+}
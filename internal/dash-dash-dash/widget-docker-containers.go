@@ -0,0 +1,252 @@
+package dashdashdash
+
+import (
+	"cmp"
+	"context"
+	"html/template"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var dockerContainersWidgetTemplate = mustParseTemplate("docker-containers.html", "widget-base.html")
+
+const (
+	defaultDockerSocketPath    = "/var/run/docker.sock"
+	defaultDockerCacheDuration = time.Minute
+	dockerContainerLabelPrefix = "dash."
+)
+
+type dockerContainersWidget struct {
+	widgetBase    `yaml:",inline"`
+	SocketPath    string                 `yaml:"socket-path"`
+	HideByDefault bool                   `yaml:"hide-by-default"`
+	RunningOnly   bool                   `yaml:"running-only"`
+	SameTab       bool                   `yaml:"same-tab"`
+	Groups        []dockerContainerGroup `yaml:"-"`
+	client        *http.Client           `yaml:"-"`
+}
+
+type dockerContainerGroup struct {
+	Title      string
+	Containers []dockerContainer
+}
+
+type dockerContainer struct {
+	Title       string
+	URL         string
+	Icon        customIconField
+	Description string
+	Image       string
+	State       string // running, exited, paused, restarting, ...
+	Health      string // healthy, unhealthy, starting or empty without a health check
+	Uptime      string
+	StatusText  string
+	StatusStyle string // ok, pending or error
+	group       string
+}
+
+// dockerContainerResponse is an entry of the Docker Engine API's
+// GET /containers/json response.
+type dockerContainerResponse struct {
+	Names  []string          `json:"Names"`
+	Image  string            `json:"Image"`
+	State  string            `json:"State"`
+	Status string            `json:"Status"`
+	Labels map[string]string `json:"Labels"`
+}
+
+func (widget *dockerContainersWidget) IsRefreshable() bool {
+	return true
+}
+
+func (widget *dockerContainersWidget) initialize() error {
+	widget.withTitle("Containers").withCacheDuration(defaultDockerCacheDuration)
+
+	if widget.SocketPath == "" {
+		widget.SocketPath = defaultDockerSocketPath
+	}
+
+	socketPath := widget.SocketPath
+	widget.client = &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				dialer := net.Dialer{}
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+		Timeout: defaultClientTimeout,
+	}
+
+	return nil
+}
+
+func (widget *dockerContainersWidget) update(ctx context.Context) {
+	containers, err := widget.fetchContainers(ctx)
+	if !widget.canContinueUpdateAfterHandlingErr(err) {
+		return
+	}
+
+	widget.Groups = groupDockerContainers(containers)
+}
+
+func (widget *dockerContainersWidget) fetchContainers(ctx context.Context) ([]dockerContainer, error) {
+	// The host is ignored since the client always dials the socket
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://docker/containers/json?all=true", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := decodeJsonFromRequest[[]dockerContainerResponse](widget.client, request)
+	if err != nil {
+		return nil, err
+	}
+
+	containers := make([]dockerContainer, 0, len(response))
+
+	for i := range response {
+		entry := &response[i]
+		labels := entry.Labels
+
+		hide := widget.HideByDefault
+		if value, exists := labels[dockerContainerLabelPrefix+"hide"]; exists {
+			hide, _ = strconv.ParseBool(value)
+		}
+
+		if hide || (widget.RunningOnly && entry.State != "running") {
+			continue
+		}
+
+		name := ""
+		if len(entry.Names) > 0 {
+			name = strings.TrimPrefix(entry.Names[0], "/")
+		}
+
+		// Labels can be set by whoever built the image, so only links that
+		// can't run scripts are kept
+		url := strings.TrimSpace(labels[dockerContainerLabelPrefix+"url"])
+		if !isSafeExtensionURL(url) {
+			url = ""
+		}
+
+		container := dockerContainer{
+			Title:       cmp.Or(labels[dockerContainerLabelPrefix+"title"], name),
+			URL:         url,
+			Icon:        newCustomIconField(labels[dockerContainerLabelPrefix+"icon"]),
+			Description: labels[dockerContainerLabelPrefix+"description"],
+			Image:       entry.Image,
+			State:       entry.State,
+			Health:      dockerContainerHealth(entry.Status),
+			group:       labels[dockerContainerLabelPrefix+"group"],
+		}
+
+		container.StatusText, container.StatusStyle = dockerContainerStatus(container.State, container.Health)
+
+		if container.State == "running" {
+			// "Up 3 hours (healthy)" -> "3 hours"
+			uptime, _, _ := strings.Cut(entry.Status, " (")
+			container.Uptime = strings.TrimPrefix(uptime, "Up ")
+		}
+
+		containers = append(containers, container)
+	}
+
+	return containers, nil
+}
+
+// dockerContainerHealth extracts the health from the status text of a
+// container, which the list endpoint doesn't return separately.
+func dockerContainerHealth(status string) string {
+	switch {
+	case strings.Contains(status, "(healthy)"):
+		return "healthy"
+	case strings.Contains(status, "(unhealthy)"):
+		return "unhealthy"
+	case strings.Contains(status, "(health: starting)"):
+		return "starting"
+	}
+
+	return ""
+}
+
+func dockerContainerStatus(state, health string) (string, string) {
+	switch {
+	case state == "running" && health == "unhealthy":
+		return "Unhealthy", "error"
+	case state == "running" && health == "starting":
+		return "Starting", "pending"
+	case state == "running":
+		return ternary(health == "healthy", "Healthy", "Running"), "ok"
+	case state == "restarting" || state == "created":
+		return strings.ToUpper(state[:1]) + state[1:], "pending"
+	case state == "":
+		return "Unknown", "error"
+	}
+
+	return strings.ToUpper(state[:1]) + state[1:], "error"
+}
+
+// groupDockerContainers groups the containers by their dash.group label.
+// Containers without a group come first, followed by the groups sorted by
+// title.
+func groupDockerContainers(containers []dockerContainer) []dockerContainerGroup {
+	slices.SortStableFunc(containers, func(a, b dockerContainer) int {
+		return cmp.Or(
+			cmp.Compare(a.group, b.group),
+			cmp.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title)),
+		)
+	})
+
+	var groups []dockerContainerGroup
+
+	for _, container := range containers {
+		if len(groups) == 0 || groups[len(groups)-1].Title != container.group {
+			groups = append(groups, dockerContainerGroup{Title: container.group})
+		}
+
+		last := &groups[len(groups)-1]
+		last.Containers = append(last.Containers, container)
+	}
+
+	return groups
+}
+
+func (widget *dockerContainersWidget) Render() template.HTML {
+	return widget.renderTemplate(widget, dockerContainersWidgetTemplate)
+}
+
+func (widget *dockerContainersWidget) data() any {
+	type containerData struct {
+		Title       string `json:"title"`
+		URL         string `json:"url"`
+		Description string `json:"description"`
+		Group       string `json:"group"`
+		Image       string `json:"image"`
+		State       string `json:"state"`
+		Health      string `json:"health"`
+		Uptime      string `json:"uptime"`
+		Status      string `json:"status"`
+	}
+
+	containers := make([]containerData, 0)
+	for _, group := range widget.Groups {
+		for _, container := range group.Containers {
+			containers = append(containers, containerData{
+				Title:       container.Title,
+				URL:         container.URL,
+				Description: container.Description,
+				Group:       group.Title,
+				Image:       container.Image,
+				State:       container.State,
+				Health:      container.Health,
+				Uptime:      container.Uptime,
+				Status:      container.StatusStyle,
+			})
+		}
+	}
+
+	return map[string]any{"containers": containers}
+}
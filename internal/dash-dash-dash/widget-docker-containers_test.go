@@ -0,0 +1,190 @@
+package dashdashdash
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+const dockerContainersStubResponse = `[
+	{
+		"Names": ["/jellyfin"],
+		"Image": "jellyfin/jellyfin:latest",
+		"State": "running",
+		"Status": "Up 3 hours (healthy)",
+		"Labels": {
+			"dash.title": "Jellyfin",
+			"dash.url": "https://jellyfin.example.com",
+			"dash.description": "Media server",
+			"dash.group": "Media"
+		}
+	},
+	{
+		"Names": ["/sonarr"],
+		"Image": "linuxserver/sonarr",
+		"State": "running",
+		"Status": "Up 5 minutes (health: starting)",
+		"Labels": {"dash.url": "javascript:alert(1)", "dash.group": "Media"}
+	},
+	{
+		"Names": ["/db"],
+		"Image": "postgres:16",
+		"State": "running",
+		"Status": "Up 2 days (unhealthy)",
+		"Labels": {"dash.hide": "false"}
+	},
+	{
+		"Names": ["/backup"],
+		"Image": "restic/restic",
+		"State": "exited",
+		"Status": "Exited (0) 1 hour ago",
+		"Labels": {}
+	},
+	{
+		"Names": ["/watchtower"],
+		"Image": "containrrr/watchtower",
+		"State": "running",
+		"Status": "Up 3 hours",
+		"Labels": {"dash.hide": "true"}
+	}
+]`
+
+// newDockerStubSocket serves the given containers from a unix socket the way
+// the Docker Engine API does and returns the path of the socket.
+func newDockerStubSocket(t *testing.T, containers string) string {
+	t.Helper()
+
+	socketPath := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("listening on unix socket: %v", err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/containers/json" || r.URL.Query().Get("all") != "true" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(containers))
+	}))
+	server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+
+	return socketPath
+}
+
+func newTestDockerContainersWidget(t *testing.T, socketPath string) *dockerContainersWidget {
+	t.Helper()
+
+	widget := &dockerContainersWidget{SocketPath: socketPath}
+	if err := widget.initialize(); err != nil {
+		t.Fatalf("initializing widget: %v", err)
+	}
+
+	return widget
+}
+
+func TestDockerContainersWidgetFromSocket(t *testing.T) {
+	widget := newTestDockerContainersWidget(t, newDockerStubSocket(t, dockerContainersStubResponse))
+	widget.update(context.Background())
+
+	if widget.Error != nil {
+		t.Fatalf("unexpected error: %v", widget.Error)
+	}
+
+	containers := make(map[string]dockerContainer)
+	for _, group := range widget.Groups {
+		for _, container := range group.Containers {
+			if container.group != group.Title {
+				t.Errorf("container %s is in group %q, expected %q", container.Title, group.Title, container.group)
+			}
+			containers[container.Title] = container
+		}
+	}
+
+	if _, exists := containers["watchtower"]; exists {
+		t.Error("container with dash.hide=true should be hidden")
+	}
+
+	if len(containers) != 4 {
+		t.Fatalf("expected 4 containers, got %d: %v", len(containers), containers)
+	}
+
+	tests := []struct {
+		title       string
+		url         string
+		description string
+		health      string
+		statusText  string
+		statusStyle string
+		uptime      string
+	}{
+		{"Jellyfin", "https://jellyfin.example.com", "Media server", "healthy", "Healthy", "ok", "3 hours"},
+		{"sonarr", "", "", "starting", "Starting", "pending", "5 minutes"},
+		{"db", "", "", "unhealthy", "Unhealthy", "error", "2 days"},
+		{"backup", "", "", "", "Exited", "error", ""},
+	}
+
+	for _, test := range tests {
+		container, exists := containers[test.title]
+		if !exists {
+			t.Errorf("container %s is missing", test.title)
+			continue
+		}
+
+		if container.URL != test.url {
+			t.Errorf("%s: expected url %q, got %q", test.title, test.url, container.URL)
+		}
+		if container.Description != test.description {
+			t.Errorf("%s: expected description %q, got %q", test.title, test.description, container.Description)
+		}
+		if container.Health != test.health {
+			t.Errorf("%s: expected health %q, got %q", test.title, test.health, container.Health)
+		}
+		if container.StatusText != test.statusText || container.StatusStyle != test.statusStyle {
+			t.Errorf(
+				"%s: expected status %q/%q, got %q/%q",
+				test.title, test.statusText, test.statusStyle, container.StatusText, container.StatusStyle,
+			)
+		}
+		if container.Uptime != test.uptime {
+			t.Errorf("%s: expected uptime %q, got %q", test.title, test.uptime, container.Uptime)
+		}
+	}
+
+	if len(widget.Groups) != 2 || widget.Groups[0].Title != "" || widget.Groups[1].Title != "Media" {
+		t.Errorf("expected the ungrouped containers followed by the Media group, got %v", widget.Groups)
+	}
+}
+
+func TestDockerContainersWidgetHideByDefaultAndRunningOnly(t *testing.T) {
+	widget := newTestDockerContainersWidget(t, newDockerStubSocket(t, dockerContainersStubResponse))
+	widget.HideByDefault = true
+	widget.RunningOnly = true
+
+	containers, err := widget.fetchContainers(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Only db opts back in with dash.hide=false and is running
+	if len(containers) != 1 || containers[0].Title != "db" {
+		t.Errorf("expected only db, got %v", containers)
+	}
+}
+
+func TestDockerContainersWidgetSocketError(t *testing.T) {
+	widget := newTestDockerContainersWidget(t, filepath.Join(t.TempDir(), "missing.sock"))
+	widget.update(context.Background())
+
+	if widget.Error == nil {
+		t.Error("expected an error when the socket doesn't exist")
+	}
+}
@@ -0,0 +1,310 @@
+package dashdashdash
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var extensionWidgetTemplate = mustParseTemplate("extension.html", "widget-base.html")
+
+const (
+	defaultExtensionCacheDuration = 30 * time.Minute
+	minExtensionHeaderCache       = time.Minute
+	extensionMaxResponseBodySize  = 1024 * 1024
+
+	extensionHeaderTitle    = "Widget-Title"
+	extensionHeaderTitleURL = "Widget-Title-URL"
+	extensionHeaderCache    = "Widget-Cache"
+)
+
+// extensionWidget shows HTML returned by an external endpoint, so that
+// widgets can be written in any language.
+type extensionWidget struct {
+	widgetBase         `yaml:",inline"`
+	URL                string               `yaml:"url"`
+	Parameters         queryParametersField `yaml:"parameters"`
+	Headers            map[string]string    `yaml:"headers"`
+	AllowDangerousHTML bool                 `yaml:"allow-potentially-dangerous-html"`
+	Content            template.HTML        `yaml:"-"`
+	titleFromConfig    bool                 `yaml:"-"`
+	titleURLFromConfig bool                 `yaml:"-"`
+}
+
+func (widget *extensionWidget) IsRefreshable() bool {
+	return true
+}
+
+func (widget *extensionWidget) initialize() error {
+	widget.titleFromConfig = widget.Title != ""
+	widget.titleURLFromConfig = widget.TitleURL != ""
+	widget.withTitle("Extension").withCacheDuration(defaultExtensionCacheDuration)
+
+	if widget.URL == "" {
+		return errors.New("url is required")
+	}
+
+	parsed, err := url.Parse(widget.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %v", err)
+	}
+
+	if len(widget.Parameters) > 0 {
+		query := widget.Parameters.toQueryString()
+		if parsed.RawQuery != "" {
+			query = parsed.RawQuery + "&" + query
+		}
+		parsed.RawQuery = query
+		widget.URL = parsed.String()
+	}
+
+	return nil
+}
+
+func (widget *extensionWidget) update(ctx context.Context) {
+	response, err := widget.fetch(ctx)
+	if err == nil {
+		widget.applyResponseHeaders(response.header)
+	}
+
+	if !widget.canContinueUpdateAfterHandlingErr(err) {
+		return
+	}
+
+	widget.Content = response.content
+}
+
+type extensionResponse struct {
+	content template.HTML
+	header  http.Header
+}
+
+func (widget *extensionWidget) fetch(ctx context.Context) (*extensionResponse, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, widget.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("User-Agent", userAgentString)
+	for key, value := range widget.Headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := defaultHTTPClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from extension", response.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, extensionMaxResponseBodySize))
+	if err != nil {
+		return nil, err
+	}
+
+	content := template.HTML(body)
+	if !widget.AllowDangerousHTML {
+		sanitized, err := sanitizeHTML(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("sanitizing response: %v", err)
+		}
+		content = template.HTML(sanitized)
+	}
+
+	return &extensionResponse{content: content, header: response.Header}, nil
+}
+
+// applyResponseHeaders lets the extension set the title, title URL and cache
+// duration of the widget, unless they're set in the config.
+func (widget *extensionWidget) applyResponseHeaders(header http.Header) {
+	if !widget.titleFromConfig {
+		widget.Title = cmp.Or(header.Get(extensionHeaderTitle), "Extension")
+	}
+
+	if !widget.titleURLFromConfig {
+		widget.TitleURL = ""
+		if titleURL := header.Get(extensionHeaderTitleURL); isSafeExtensionURL(titleURL) && titleURL != "" {
+			widget.TitleURL = titleURL
+		}
+	}
+
+	if value := header.Get(extensionHeaderCache); value != "" && widget.CustomCacheDuration == 0 {
+		duration, err := parseDurationField(value)
+		if err != nil {
+			slog.Warn("Ignoring invalid cache duration from extension", "url", redactURL(widget.URL), "value", value)
+			return
+		}
+
+		// Keeps an extension from being requested on every background refresh
+		widget.cacheDuration = max(time.Duration(duration), minExtensionHeaderCache)
+	}
+}
+
+func (widget *extensionWidget) Render() template.HTML {
+	return widget.renderTemplate(widget, extensionWidgetTemplate)
+}
+
+func (widget *extensionWidget) data() any {
+	return map[string]any{"html": widget.Content}
+}
+
+var extensionAllowedElements = map[atom.Atom]bool{
+	atom.A: true, atom.Abbr: true, atom.B: true, atom.Blockquote: true, atom.Br: true,
+	atom.Code: true, atom.Dd: true, atom.Del: true, atom.Details: true, atom.Div: true,
+	atom.Dl: true, atom.Dt: true, atom.Em: true, atom.Figcaption: true, atom.Figure: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Hr: true, atom.I: true, atom.Img: true, atom.Ins: true, atom.Kbd: true, atom.Li: true,
+	atom.Mark: true, atom.Ol: true, atom.P: true, atom.Pre: true, atom.Q: true, atom.S: true,
+	atom.Small: true, atom.Span: true, atom.Strong: true, atom.Sub: true, atom.Summary: true,
+	atom.Sup: true, atom.Table: true, atom.Tbody: true, atom.Td: true, atom.Tfoot: true,
+	atom.Th: true, atom.Thead: true, atom.Time: true, atom.Tr: true, atom.U: true, atom.Ul: true,
+}
+
+// Removed along with their content, any other element that isn't allowed is
+// replaced by its content.
+var extensionRemovedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Iframe: true, atom.Frame: true, atom.Frameset: true,
+	atom.Object: true, atom.Embed: true, atom.Noscript: true, atom.Template: true, atom.Svg: true,
+	atom.Math: true, atom.Form: true, atom.Textarea: true, atom.Select: true, atom.Button: true,
+	atom.Input: true, atom.Link: true, atom.Meta: true, atom.Base: true, atom.Title: true,
+	atom.Head: true,
+}
+
+var extensionAllowedAttributes = map[string]bool{
+	"class": true, "title": true, "lang": true, "dir": true,
+	"data-dynamic-relative-time": true,
+}
+
+var extensionAllowedElementAttributes = map[atom.Atom]map[string]bool{
+	atom.A:       {"href": true, "target": true},
+	atom.Img:     {"src": true, "alt": true, "width": true, "height": true, "loading": true},
+	atom.Td:      {"colspan": true, "rowspan": true},
+	atom.Th:      {"colspan": true, "rowspan": true},
+	atom.Ol:      {"start": true},
+	atom.Time:    {"datetime": true},
+	atom.Details: {"open": true},
+}
+
+// sanitizeHTML keeps the elements and attributes of the input that can't run
+// scripts or break out of the widget. Links may only use http, https or
+// mailto.
+func sanitizeHTML(input string) (string, error) {
+	context := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+
+	nodes, err := html.ParseFragment(strings.NewReader(input), context)
+	if err != nil {
+		return "", err
+	}
+
+	var output strings.Builder
+	for _, node := range nodes {
+		writeSanitizedNode(&output, node)
+	}
+
+	return output.String(), nil
+}
+
+func writeSanitizedNode(output *strings.Builder, node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		output.WriteString(html.EscapeString(node.Data))
+		return
+	case html.ElementNode:
+	default:
+		// Comments and doctypes
+		return
+	}
+
+	if extensionRemovedElements[node.DataAtom] || node.Namespace != "" {
+		return
+	}
+
+	allowed := extensionAllowedElements[node.DataAtom]
+
+	if allowed {
+		output.WriteByte('<')
+		output.WriteString(node.Data)
+		writeSanitizedAttributes(output, node)
+		output.WriteByte('>')
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		writeSanitizedNode(output, child)
+	}
+
+	if allowed && !isVoidElement(node.DataAtom) {
+		output.WriteString("</")
+		output.WriteString(node.Data)
+		output.WriteByte('>')
+	}
+}
+
+func writeSanitizedAttributes(output *strings.Builder, node *html.Node) {
+	hasTarget := false
+
+	for _, attr := range node.Attr {
+		key := strings.ToLower(attr.Key)
+		if attr.Namespace != "" || !(extensionAllowedAttributes[key] || extensionAllowedElementAttributes[node.DataAtom][key]) {
+			continue
+		}
+
+		if (key == "href" || key == "src") && !isSafeExtensionURL(attr.Val) {
+			continue
+		}
+
+		if key == "target" {
+			hasTarget = true
+		}
+
+		output.WriteByte(' ')
+		output.WriteString(key)
+		output.WriteString(`="`)
+		output.WriteString(html.EscapeString(attr.Val))
+		output.WriteByte('"')
+	}
+
+	if hasTarget {
+		output.WriteString(` rel="noreferrer"`)
+	}
+}
+
+func isVoidElement(a atom.Atom) bool {
+	return a == atom.Br || a == atom.Hr || a == atom.Img
+}
+
+// isSafeExtensionURL allows relative URLs and the http, https and mailto
+// schemes.
+func isSafeExtensionURL(rawURL string) bool {
+	rawURL = strings.TrimSpace(rawURL)
+
+	// Browsers ignore tabs and newlines within URLs, which would hide the scheme
+	if strings.ContainsFunc(rawURL, func(r rune) bool { return r < 0x20 || r == 0x7f }) {
+		return false
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,84 @@
+package dashdashdash
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+)
+
+var groupWidgetTemplate = mustParseTemplate("group.html", "widget-base.html")
+
+// groupWidget shows its widgets as tabs within a single card. The widgets are
+// still updated on their own, the group itself has nothing to update.
+type groupWidget struct {
+	widgetBase `yaml:",inline"`
+	Widgets    widgets `yaml:"widgets"`
+}
+
+// containerWidget is implemented by widgets that contain other widgets, so
+// that the nested widgets can be found, updated and refreshed like any other.
+type containerWidget interface {
+	childWidgets() []widget
+}
+
+func (widget *groupWidget) childWidgets() []widget {
+	return widget.Widgets
+}
+
+func (widget *groupWidget) initialize() error {
+	widget.withTitle("Group").withError(nil)
+	widget.HideHeader = true
+
+	if len(widget.Widgets) == 0 {
+		return errors.New("at least one widget is required")
+	}
+
+	for _, child := range widget.Widgets {
+		if _, ok := child.(containerWidget); ok {
+			return fmt.Errorf("%s widgets can't be nested inside a group", child.GetType())
+		}
+
+		if err := child.initialize(); err != nil {
+			return formatWidgetInitError(err, child)
+		}
+	}
+
+	return nil
+}
+
+func (widget *groupWidget) Render() template.HTML {
+	return widget.renderTemplate(widget, groupWidgetTemplate)
+}
+
+func (widget *groupWidget) data() any {
+	children := make([]widgetDataResponse, 0, len(widget.Widgets))
+	for _, child := range widget.Widgets {
+		children = append(children, newWidgetDataResponse(child))
+	}
+
+	return map[string]any{"widgets": children}
+}
+
+// appendWidgetsWithChildren appends the widgets along with the ones nested in
+// them.
+func appendWidgetsWithChildren(all []widget, list []widget) []widget {
+	for _, w := range list {
+		all = append(all, w)
+
+		if container, ok := w.(containerWidget); ok {
+			all = appendWidgetsWithChildren(all, container.childWidgets())
+		}
+	}
+
+	return all
+}
+
+// allWidgets returns every widget of the page, including nested ones.
+func (p *page) allWidgets() []widget {
+	all := appendWidgetsWithChildren(nil, p.HeadWidgets)
+	for c := range p.Columns {
+		all = appendWidgetsWithChildren(all, p.Columns[c].Widgets)
+	}
+
+	return all
+}
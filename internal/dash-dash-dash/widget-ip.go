@@ -208,3 +208,22 @@ func (widget *ipAddressWidget) fetchCountryCode(ctx context.Context, ip string)
 func (widget *ipAddressWidget) Render() template.HTML {
 	return widget.renderTemplate(widget, ipAddressWidgetTemplate)
 }
+
+func (widget *ipAddressWidget) data() any {
+	type localIPData struct {
+		Interface string `json:"interface"`
+		Address   string `json:"address"`
+	}
+
+	localIPs := make([]localIPData, 0, len(widget.LocalIPs))
+	for _, ip := range widget.LocalIPs {
+		localIPs = append(localIPs, localIPData{Interface: ip.Label, Address: ip.Value})
+	}
+
+	return map[string]any{
+		"hostname":     widget.Hostname,
+		"local_ips":    localIPs,
+		"public_ip":    widget.PublicIP,
+		"public_label": widget.PublicLabel,
+	}
+}
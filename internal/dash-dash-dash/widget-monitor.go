@@ -1,22 +1,44 @@
 package dashdashdash
 
 import (
+	"bytes"
+	"cmp"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
+	"io"
+	"math"
 	"net"
 	"net/http"
 	"net/url"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
-const uptimeHistoryMaxEntries = 10
-const uptimeHistoryMaxURLs = 200 // Limit total unique URLs to prevent memory leak
+const uptimeHistoryMaxURLs = 200 // Limit total tracked sites to prevent memory leak
+
+// History is kept for long enough to calculate the 7 day uptime, independently
+// of how many checks each widget displays.
+const (
+	uptimeHistoryRetention        = 7 * 24 * time.Hour
+	uptimeHistoryMaxEntries       = 10000
+	uptimeHistoryFile             = "uptime-history.json"
+	uptimeHistorySnapshotInterval = 5 * time.Minute
+	uptimeHistoryKeySeparator     = "\n" // can't appear in URLs, which older versions used as keys
+	uptimeStatsPercentile         = 0.95
+	defaultMonitorHistoryDepth    = 10
+	maxMonitorHistoryDepth        = 30
+)
 
 var uptimeHistory = newUptimeHistoryStore(uptimeHistoryMaxEntries)
 
@@ -32,20 +54,31 @@ var (
 	startupGraceOnce           sync.Once         // Ensure startup delay happens only once
 )
 
+type uptimeHistoryEntry struct {
+	Time         time.Time `json:"t"`
+	Status       int       `json:"s"`
+	ResponseTime int64     `json:"r,omitempty"` // milliseconds, only set for checks that got a response
+}
+
 type uptimeHistoryStore struct {
 	mu         sync.Mutex
 	max        int
 	maxURLs    int
-	store      map[string][]int
-	accessTime map[string]time.Time // Track last access for LRU eviction
+	store      map[string][]uptimeHistoryEntry
+	accessTime map[string]time.Time            // Track last access for LRU eviction
+	legacy     map[string][]uptimeHistoryEntry // history saved per URL by older versions, see record
+	storage    *dataStorage
+	dirty      bool
+	saveMu     sync.Mutex // Keeps snapshots in order without holding mu during disk I/O
 }
 
 func newUptimeHistoryStore(maxEntries int) *uptimeHistoryStore {
 	return &uptimeHistoryStore{
 		max:        maxEntries,
 		maxURLs:    uptimeHistoryMaxURLs,
-		store:      make(map[string][]int),
+		store:      make(map[string][]uptimeHistoryEntry),
 		accessTime: make(map[string]time.Time),
+		legacy:     make(map[string][]uptimeHistoryEntry),
 	}
 }
 
@@ -56,7 +89,97 @@ const (
 	UptimeDown    = 2
 )
 
-func (u *uptimeHistoryStore) record(url string, status int) {
+// useStorage loads the history saved under the given storage and snapshots to
+// it from then on. The saved history is only loaded the first time a path is
+// used, so that a config reload doesn't replace newer history kept in memory.
+func (u *uptimeHistoryStore) useStorage(storage *dataStorage) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if storage == nil || (u.storage != nil && u.storage.path == storage.path) {
+		u.storage = storage
+		return nil
+	}
+
+	u.storage = storage
+
+	var saved map[string][]uptimeHistoryEntry
+	if _, err := storage.load(uptimeHistoryFile, &saved); err != nil {
+		return fmt.Errorf("loading uptime history: %v", err)
+	}
+
+	now := time.Now()
+	for key, entries := range saved {
+		entries = u.trim(entries, now)
+		if len(entries) == 0 {
+			continue
+		}
+
+		if !strings.Contains(key, uptimeHistoryKeySeparator) {
+			if len(u.legacy) < u.maxURLs {
+				u.legacy[key] = entries
+			}
+			continue
+		}
+
+		if _, exists := u.store[key]; exists || len(u.store) >= u.maxURLs {
+			continue
+		}
+
+		u.store[key] = entries
+		u.accessTime[key] = now
+	}
+
+	return nil
+}
+
+// snapshot writes the history to disk if it changed since the last snapshot.
+// The history is copied first so that checks aren't held up by the write.
+func (u *uptimeHistoryStore) snapshot() error {
+	u.saveMu.Lock()
+	defer u.saveMu.Unlock()
+
+	u.mu.Lock()
+	if u.storage == nil || !u.dirty {
+		u.mu.Unlock()
+		return nil
+	}
+
+	storage := u.storage
+	store := make(map[string][]uptimeHistoryEntry, len(u.store))
+	for key, entries := range u.store {
+		store[key] = slices.Clone(entries)
+	}
+	u.dirty = false
+	u.mu.Unlock()
+
+	if err := storage.save(uptimeHistoryFile, store); err != nil {
+		u.mu.Lock()
+		u.dirty = true
+		u.mu.Unlock()
+
+		return fmt.Errorf("saving uptime history: %v", err)
+	}
+
+	return nil
+}
+
+func (u *uptimeHistoryStore) trim(entries []uptimeHistoryEntry, now time.Time) []uptimeHistoryEntry {
+	cutoff := now.Add(-uptimeHistoryRetention)
+	i := 0
+	for i < len(entries) && entries[i].Time.Before(cutoff) {
+		i++
+	}
+	entries = entries[i:]
+
+	if len(entries) > u.max {
+		entries = entries[len(entries)-u.max:]
+	}
+
+	return entries
+}
+
+func (u *uptimeHistoryStore) record(key string, status int, responseTime time.Duration) {
 	// Validate status value
 	if status < UptimeUnknown || status > UptimeDown {
 		status = UptimeUnknown
@@ -66,7 +189,7 @@ func (u *uptimeHistoryStore) record(url string, status int) {
 	defer u.mu.Unlock()
 	
 	// Evict oldest URLs if we exceed the limit (LRU eviction)
-	if _, exists := u.store[url]; !exists && len(u.store) >= u.maxURLs {
+	if _, exists := u.store[key]; !exists && len(u.store) >= u.maxURLs {
 		var oldestURL string
 		var oldestTime time.Time
 		first := true
@@ -85,30 +208,128 @@ func (u *uptimeHistoryStore) record(url string, status int) {
 		}
 	}
 	
-	list := u.store[url]
-	list = append(list, status)
-	if len(list) > u.max {
-		list = list[len(list)-u.max:]
+	// History saved before it was kept per site carries over to every site
+	// that checks the same URL
+	if _, exists := u.store[key]; !exists {
+		url := key[strings.LastIndex(key, uptimeHistoryKeySeparator)+1:]
+		if entries, exists := u.legacy[url]; exists {
+			u.store[key] = slices.Clone(entries)
+		}
 	}
-	u.store[url] = list
-	u.accessTime[url] = time.Now()
+
+	now := time.Now()
+	entry := uptimeHistoryEntry{Time: now, Status: status}
+	if responseTime > 0 {
+		// Round up so that sub-millisecond responses still count towards the averages
+		entry.ResponseTime = max(1, responseTime.Milliseconds())
+	}
+
+	u.store[key] = u.trim(append(u.store[key], entry), now)
+	u.accessTime[key] = now
+	u.dirty = true
 }
 
-func (u *uptimeHistoryStore) get(url string) []int {
+// get returns the statuses of the last depth checks.
+func (u *uptimeHistoryStore) get(key string, depth int) []int {
 	u.mu.Lock()
 	defer u.mu.Unlock()
-	list := u.store[url]
+	list := u.store[key]
+	if len(list) > depth {
+		list = list[len(list)-depth:]
+	}
+
 	result := make([]int, len(list))
-	copy(result, list)
+	for i := range list {
+		result[i] = list[i].Status
+	}
 	
 	// Update access time for LRU
 	if len(list) > 0 {
-		u.accessTime[url] = time.Now()
+		u.accessTime[key] = time.Now()
 	}
 	
 	return result
 }
 
+type uptimeStats struct {
+	Window       time.Duration
+	Checks       int     // checks with a known result
+	Uptime       float64 // percentage of checks that were up
+	AvgResponse  time.Duration
+	P95Response  time.Duration
+	LastIncident time.Time // when the site last went down, zero if it hasn't
+}
+
+func (s uptimeStats) WindowLabel() string {
+	return formatStatsWindow(s.Window)
+}
+
+// stats calculates the uptime and response times of the checks within the
+// window. Checks with an unknown result are ignored and response times only
+// include checks where the site was up.
+func (u *uptimeHistoryStore) stats(key string, window time.Duration) uptimeStats {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	stats := uptimeStats{Window: window}
+	cutoff := time.Now().Add(-window)
+	responseTimes := make([]int64, 0, len(u.store[key]))
+	var up int
+	wasDown := false
+
+	for _, entry := range u.store[key] {
+		if entry.Time.Before(cutoff) || entry.Status == UptimeUnknown {
+			continue
+		}
+
+		stats.Checks++
+		if entry.Status == UptimeUp {
+			up++
+			wasDown = false
+			if entry.ResponseTime > 0 {
+				responseTimes = append(responseTimes, entry.ResponseTime)
+			}
+			continue
+		}
+
+		if !wasDown {
+			stats.LastIncident = entry.Time
+			wasDown = true
+		}
+	}
+
+	if stats.Checks > 0 {
+		stats.Uptime = float64(up) / float64(stats.Checks) * 100
+	}
+
+	if len(responseTimes) > 0 {
+		var total int64
+		for _, rt := range responseTimes {
+			total += rt
+		}
+		stats.AvgResponse = time.Duration(total/int64(len(responseTimes))) * time.Millisecond
+
+		slices.Sort(responseTimes)
+		rank := int(math.Ceil(uptimeStatsPercentile*float64(len(responseTimes)))) - 1
+		stats.P95Response = time.Duration(responseTimes[rank]) * time.Millisecond
+	}
+
+	return stats
+}
+
+func formatStatsWindow(d time.Duration) string {
+	switch {
+	case d > 24*time.Hour && d%(24*time.Hour) == 0:
+		return strconv.Itoa(int(d/(24*time.Hour))) + "d"
+	case d%time.Hour == 0:
+		return strconv.Itoa(int(d/time.Hour)) + "h"
+	case d%time.Minute == 0:
+		return strconv.Itoa(int(d/time.Minute)) + "m"
+	}
+
+	return d.String()
+}
+
 var (
 	monitorWidgetTemplate        = mustParseTemplate("monitor.html", "widget-base.html")
 	monitorWidgetCompactTemplate = mustParseTemplate("monitor-compact.html", "widget-base.html")
@@ -119,7 +340,7 @@ type monitorWidget struct {
 	Sites      []struct {
 		*SiteStatusRequest `yaml:",inline"`
 		Status             *siteStatus     `yaml:"-"`
-		URL                string          `yaml:"-"`
+		URL                string          `yaml:"-"` // empty when the site can't be opened in a browser
 		ErrorURL           string          `yaml:"error-url"`
 		Title              string          `yaml:"title"`
 		Icon               customIconField `yaml:"icon"`
@@ -128,21 +349,55 @@ type monitorWidget struct {
 		StatusStyle        string          `yaml:"-"`
 		AltStatusCodes     []int           `yaml:"alt-status-codes"`
 		History            []int           `yaml:"-"` // last N uptime status: 0=unknown, 1=up, 2=down
+		Stats              []uptimeStats   `yaml:"-"` // one per stats window
 		IsLocal            bool            `yaml:"-"` // true if site is on local network
+		CertificateWarning bool            `yaml:"-"` // certificate expires within cert-expiry-warning
+		Notify             []string        `yaml:"notify"`
+		NotifyAfter        int             `yaml:"notify-after"`
+		// see the comment in bookmarksWidget for why both fields are needed
+		NotifyRecoveryRaw *bool      `yaml:"notify-recovery"`
+		NotifyRecovery    bool       `yaml:"-"`
+		notifiers         []notifier `yaml:"-"`
 	} `yaml:"sites"`
-	Style               string `yaml:"style"`
-	ShowFailingOnly     bool   `yaml:"show-failing-only"`
-	ShowInternetStatus  bool   `yaml:"show-internet-status"`
-	HasFailing          bool   `yaml:"-"`
-	InternetStatus      *siteStatus `yaml:"-"`
-	InternetAvailable   bool        `yaml:"-"`
+	Style              string          `yaml:"style"`
+	HistoryDepth       int             `yaml:"history-depth"`
+	StatsWindows       []durationField `yaml:"stats-windows"`
+	CertExpiryWarning  durationField   `yaml:"cert-expiry-warning"`
+	ShowFailingOnly    bool            `yaml:"show-failing-only"`
+	ShowInternetStatus bool            `yaml:"show-internet-status"`
+	HasFailing         bool            `yaml:"-"`
+	InternetStatus     *siteStatus     `yaml:"-"`
+	InternetAvailable  bool            `yaml:"-"`
+	alertKey           string          `yaml:"-"` // position of the widget in the config, see monitorAlerts
 }
 func (widget *monitorWidget) IsRefreshable() bool {
 	return true
 }
 func (widget *monitorWidget) initialize() error {
 	widget.withTitle("Monitor").withCacheDuration(5 * time.Minute)
+
+	if widget.HistoryDepth == 0 {
+		widget.HistoryDepth = defaultMonitorHistoryDepth
+	} else if widget.HistoryDepth < 0 || widget.HistoryDepth > maxMonitorHistoryDepth {
+		return fmt.Errorf("history-depth must be between 1 and %d", maxMonitorHistoryDepth)
+	}
+
+	if len(widget.StatsWindows) == 0 {
+		widget.StatsWindows = []durationField{durationField(24 * time.Hour), durationField(7 * 24 * time.Hour)}
+	}
+
+	for _, window := range widget.StatsWindows {
+		if time.Duration(window) > uptimeHistoryRetention {
+			return fmt.Errorf("stats-windows cannot be longer than %s", formatStatsWindow(uptimeHistoryRetention))
+		}
+	}
 	
+	if widget.CertExpiryWarning == 0 {
+		widget.CertExpiryWarning = durationField(defaultCertExpiryWarning)
+	} else if widget.CertExpiryWarning < 0 {
+		return errors.New("cert-expiry-warning must be positive")
+	}
+
 	// Validate and determine which sites are local
 	for i := range widget.Sites {
 		site := &widget.Sites[i]
@@ -152,19 +407,30 @@ func (widget *monitorWidget) initialize() error {
 			return fmt.Errorf("site %d: url is required", i+1)
 		}
 		
-		// Ensure URL has a scheme
-		if !strings.HasPrefix(site.DefaultURL, "http://") && !strings.HasPrefix(site.DefaultURL, "https://") {
-			return fmt.Errorf("site %d (%s): url must start with http:// or https://", i+1, site.Title)
+		if err := validateMonitorURL(site.DefaultURL); err != nil {
+			return fmt.Errorf("site %d (%s): url: %v", i+1, site.Title, err)
 		}
 		
 		// Validate check-url if provided
 		if site.CheckURL != "" {
-			if !strings.HasPrefix(site.CheckURL, "http://") && !strings.HasPrefix(site.CheckURL, "https://") {
-				return fmt.Errorf("site %d (%s): check-url must start with http:// or https://", i+1, site.Title)
+			if err := validateMonitorURL(site.CheckURL); err != nil {
+				return fmt.Errorf("site %d (%s): check-url: %v", i+1, site.Title, err)
 			}
 		}
 		
+		if err := site.SiteStatusRequest.initialize(); err != nil {
+			return fmt.Errorf("site %d (%s): %v", i+1, site.Title, err)
+		}
+
 		site.IsLocal = isLocalURL(site.DefaultURL)
+
+		if site.NotifyAfter == 0 {
+			site.NotifyAfter = defaultNotifyAfterFailures
+		} else if site.NotifyAfter < 0 {
+			return fmt.Errorf("site %d (%s): notify-after must be positive", i+1, site.Title)
+		}
+
+		site.NotifyRecovery = site.NotifyRecoveryRaw == nil || *site.NotifyRecoveryRaw
 	}
 	
 	return nil
@@ -215,21 +481,41 @@ func (widget *monitorWidget) update(ctx context.Context) {
 			status := &statuses[j]
 			site.Status = status
 
-			isUp := (status.Code == 200 || slices.Contains(site.AltStatusCodes, status.Code)) && status.Error == nil
+			isUp := status.isUp(site.AltStatusCodes)
 			uptimeStatus := UptimeDown
 			if isUp {
 				uptimeStatus = UptimeUp
 			}
-			uptimeHistory.record(site.DefaultURL, uptimeStatus)
-			site.History = uptimeHistory.get(site.DefaultURL)
+			uptimeHistory.record(widget.siteHistoryKey(i), uptimeStatus, ternary(status.Error == nil, status.ResponseTime, 0))
+			widget.loadSiteHistory(i)
 
 			if status.Error != nil && site.ErrorURL != "" {
-				site.URL = site.ErrorURL
+				site.URL = siteLinkURL(site.ErrorURL)
 			} else {
-				site.URL = site.DefaultURL
+				site.URL = siteLinkURL(site.DefaultURL)
+			}
+			site.CertificateWarning = isUp && status.Certificate != nil &&
+				time.Until(status.Certificate.NotAfter) < time.Duration(widget.CertExpiryWarning)
+
+			if status.CertificateExpired {
+				site.StatusText = "Certificate Expired"
+				site.StatusStyle = "expired"
+			} else if status.Text != "" && !status.TimedOut {
+				// Non-HTTP checks describe their own result
+				site.StatusText = status.Text
+				site.StatusStyle = ternary(status.Error == nil, "ok", "error")
+			} else {
+				site.StatusText = statusCodeToText(status.Code, status.TimedOut, status.Error, site.AltStatusCodes)
+				site.StatusStyle = statusCodeToStyle(status.Code, status.TimedOut, status.Error, site.AltStatusCodes)
+			}
+
+			if len(site.notifiers) > 0 {
+				title := ternary(site.Title == "", site.DefaultURL, site.Title)
+				n := recordMonitorCheck(widget.alertKey, site.DefaultURL, title, site.StatusText, uptimeStatus, site.NotifyAfter, site.NotifyRecovery)
+				if n != nil {
+					dispatchNotification(site.notifiers, n)
+				}
 			}
-			site.StatusText = statusCodeToText(status.Code, status.TimedOut, status.Error, site.AltStatusCodes)
-			site.StatusStyle = statusCodeToStyle(status.Code, status.TimedOut, status.Error, site.AltStatusCodes)
 		}
 	}
 
@@ -238,15 +524,15 @@ func (widget *monitorWidget) update(ctx context.Context) {
 		site := &widget.Sites[i]
 		if !internetUp && !site.IsLocal {
 			// Record unknown state in history
-			uptimeHistory.record(site.DefaultURL, UptimeUnknown)
-			site.History = uptimeHistory.get(site.DefaultURL)
+			uptimeHistory.record(widget.siteHistoryKey(i), UptimeUnknown, 0)
+			widget.loadSiteHistory(i)
 			
 			if site.Status == nil {
 				site.Status = &siteStatus{}
 			}
 			site.StatusText = "Unknown"
 			site.StatusStyle = "unknown"
-			site.URL = site.DefaultURL
+			site.URL = siteLinkURL(site.DefaultURL)
 		}
 	}
 
@@ -254,7 +540,7 @@ func (widget *monitorWidget) update(ctx context.Context) {
 	widget.HasFailing = false
 	for i := range widget.Sites {
 		site := &widget.Sites[i]
-		if site.StatusStyle == "error" {
+		if site.StatusStyle == "error" || site.StatusStyle == "expired" {
 			widget.HasFailing = true
 			break
 		}
@@ -270,6 +556,49 @@ func (widget *monitorWidget) update(ctx context.Context) {
 	widget.withError(nil).scheduleNextUpdate()
 }
 
+// resolveNotifiers looks up the notifiers referenced by the sites. Done after
+// initialize since the notifiers are configured outside of the widget.
+func (widget *monitorWidget) resolveNotifiers(available notifiers) error {
+	for i := range widget.Sites {
+		site := &widget.Sites[i]
+		site.notifiers = site.notifiers[:0]
+
+		for _, name := range site.Notify {
+			notifier, exists := available[name]
+			if !exists {
+				return fmt.Errorf("site %d (%s): notifier %s is not defined in notifications", i+1, site.Title, name)
+			}
+			site.notifiers = append(site.notifiers, notifier)
+		}
+	}
+
+	return nil
+}
+
+// siteHistoryKey keeps the history of sites that check the same URL from
+// different widgets or under different titles separate, the same way as
+// monitorAlertKey does for alerts. The URL is always last, see record.
+func (widget *monitorWidget) siteHistoryKey(i int) string {
+	site := &widget.Sites[i]
+	return strings.Join([]string{widget.alertKey, site.Title, site.DefaultURL}, uptimeHistoryKeySeparator)
+}
+
+func (widget *monitorWidget) loadSiteHistory(i int) {
+	site := &widget.Sites[i]
+	site.History = uptimeHistory.get(widget.siteHistoryKey(i), widget.HistoryDepth)
+	site.Stats = widget.siteStats(i)
+}
+
+func (widget *monitorWidget) siteStats(i int) []uptimeStats {
+	key := widget.siteHistoryKey(i)
+	stats := make([]uptimeStats, len(widget.StatsWindows))
+	for j, window := range widget.StatsWindows {
+		stats[j] = uptimeHistory.stats(key, time.Duration(window))
+	}
+
+	return stats
+}
+
 func (widget *monitorWidget) Render() template.HTML {
 	if widget.Style == "compact" {
 		return widget.renderTemplate(widget, monitorWidgetCompactTemplate)
@@ -277,6 +606,237 @@ func (widget *monitorWidget) Render() template.HTML {
 	return widget.renderTemplate(widget, monitorWidgetTemplate)
 }
 
+type monitorStatsResponse struct {
+	Title string                     `json:"title"`
+	Sites []monitorSiteStatsResponse `json:"sites"`
+}
+
+type monitorSiteStatsResponse struct {
+	Title       string                `json:"title"`
+	URL         string                `json:"url"`
+	Status      string                `json:"status"`
+	Stats       []uptimeStatsResponse `json:"stats"`
+	Certificate *certificateResponse  `json:"certificate,omitempty"`
+}
+
+type certificateResponse struct {
+	ExpiresAt time.Time `json:"expires_at"`
+	DaysLeft  int       `json:"days_left"`
+	Issuer    string    `json:"issuer"`
+	DNSNames  []string  `json:"dns_names"`
+}
+
+type uptimeStatsResponse struct {
+	Window        string     `json:"window"`
+	Checks        int        `json:"checks"`
+	UptimePercent *float64   `json:"uptime_percent"`
+	AvgResponseMs *int64     `json:"avg_response_ms"`
+	P95ResponseMs *int64     `json:"p95_response_ms"`
+	LastIncident  *time.Time `json:"last_incident"`
+}
+
+func (a *application) handleMonitorStatsRequest(w http.ResponseWriter, r *http.Request) {
+	found, page, ok := a.widgetForRequest(w, r)
+	if !ok {
+		return
+	}
+
+	widget, ok := found.(*monitorWidget)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Widget is not a monitor"))
+		return
+	}
+
+	page.mu.RLock()
+	response := monitorStatsResponse{
+		Title: widget.Title,
+		Sites: make([]monitorSiteStatsResponse, len(widget.Sites)),
+	}
+
+	for i := range widget.Sites {
+		site := &widget.Sites[i]
+		siteResponse := monitorSiteStatsResponse{
+			Title:  site.Title,
+			URL:    redactURL(site.DefaultURL),
+			Status: ternary(site.StatusStyle == "", "unknown", site.StatusStyle),
+		}
+
+		siteResponse.Stats = newUptimeStatsResponses(widget.siteStats(i))
+		if site.Status != nil {
+			siteResponse.Certificate = newCertificateResponse(site.Status.Certificate)
+		}
+
+		response.Sites[i] = siteResponse
+	}
+	page.mu.RUnlock()
+
+	writeJSONResponse(w, http.StatusOK, response)
+}
+
+func newUptimeStatsResponses(allStats []uptimeStats) []uptimeStatsResponse {
+	responses := make([]uptimeStatsResponse, 0, len(allStats))
+
+	for _, stats := range allStats {
+		response := uptimeStatsResponse{
+			Window: stats.WindowLabel(),
+			Checks: stats.Checks,
+		}
+
+		if stats.Checks > 0 {
+			response.UptimePercent = &stats.Uptime
+		}
+
+		if stats.AvgResponse > 0 {
+			avg, p95 := stats.AvgResponse.Milliseconds(), stats.P95Response.Milliseconds()
+			response.AvgResponseMs, response.P95ResponseMs = &avg, &p95
+		}
+
+		if !stats.LastIncident.IsZero() {
+			response.LastIncident = &stats.LastIncident
+		}
+
+		responses = append(responses, response)
+	}
+
+	return responses
+}
+
+func newCertificateResponse(certificate *siteCertificate) *certificateResponse {
+	if certificate == nil {
+		return nil
+	}
+
+	return &certificateResponse{
+		ExpiresAt: certificate.NotAfter,
+		DaysLeft:  certificate.DaysLeft(),
+		Issuer:    certificate.Issuer,
+		DNSNames:  certificate.DNSNames,
+	}
+}
+
+type monitorSiteData struct {
+	Title          string                `json:"title"`
+	URL            string                `json:"url"`
+	Status         string                `json:"status"`
+	StatusText     string                `json:"status_text"`
+	StatusCode     int                   `json:"status_code,omitempty"`
+	ResponseTimeMs *int64                `json:"response_time_ms"`
+	Error          *string               `json:"error"`
+	History        []string              `json:"history"`
+	Stats          []uptimeStatsResponse `json:"stats"`
+	Certificate    *certificateResponse  `json:"certificate,omitempty"`
+}
+
+var uptimeStatusNames = map[int]string{
+	UptimeUnknown: "unknown",
+	UptimeUp:      "up",
+	UptimeDown:    "down",
+}
+
+// Credentials, headers and request bodies of the checks are left out.
+func (widget *monitorWidget) data() any {
+	sites := make([]monitorSiteData, 0, len(widget.Sites))
+
+	for i := range widget.Sites {
+		site := &widget.Sites[i]
+		siteData := monitorSiteData{
+			Title:      site.Title,
+			URL:        redactURL(site.DefaultURL),
+			Status:     ternary(site.StatusStyle == "", "unknown", site.StatusStyle),
+			StatusText: site.StatusText,
+			History:    make([]string, 0, len(site.History)),
+			Stats:      newUptimeStatsResponses(site.Stats),
+		}
+
+		for _, status := range site.History {
+			siteData.History = append(siteData.History, uptimeStatusNames[status])
+		}
+
+		if status := site.Status; status != nil {
+			siteData.StatusCode = status.Code
+			siteData.Certificate = newCertificateResponse(status.Certificate)
+
+			if status.Error != nil {
+				message := status.Error.Error()
+				siteData.Error = &message
+			} else if site.StatusStyle != "unknown" {
+				responseTime := status.ResponseTime.Milliseconds()
+				siteData.ResponseTimeMs = &responseTime
+			}
+		}
+
+		sites = append(sites, siteData)
+	}
+
+	return map[string]any{
+		"internet_available": widget.InternetAvailable,
+		"sites":              sites,
+	}
+}
+
+// Sites that haven't been checked yet are left out.
+func (widget *monitorWidget) collectMetrics(m *metricsWriter, labels []string) {
+	for i := range widget.Sites {
+		site := &widget.Sites[i]
+		if site.Status == nil || len(site.History) == 0 {
+			continue
+		}
+
+		siteLabels := slices.Concat(labels, []string{
+			"site", site.Title,
+			"url", redactURL(site.DefaultURL),
+		})
+
+		isUp := site.StatusStyle != "unknown" && site.Status.isUp(site.AltStatusCodes)
+		m.gauge("dash_monitor_site_up", "Whether the last check of a monitored site succeeded.", float64(ternary(isUp, 1, 0)), siteLabels...)
+
+		if site.Status.Error == nil {
+			m.gauge("dash_monitor_site_response_time_seconds", "Response time of the last check of a monitored site.", site.Status.ResponseTime.Seconds(), siteLabels...)
+		}
+
+		if certificate := site.Status.Certificate; certificate != nil {
+			m.gauge("dash_monitor_site_certificate_expiry_timestamp_seconds", "Expiry time of the certificate presented by a monitored site.", float64(certificate.NotAfter.Unix()), siteLabels...)
+		}
+	}
+}
+
+// siteLinkURL returns the URL if a browser can open it, so that sites checked
+// with tcp:// or dns:// aren't shown as links that do nothing.
+func siteLinkURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return ""
+	}
+
+	return rawURL
+}
+
+// redactURL removes the parts of a URL that commonly carry secrets, the
+// userinfo and the values of query parameters such as ?token=, before it's
+// shown outside of the config.
+func redactURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		withoutQuery, _, _ := strings.Cut(rawURL, "?")
+		return withoutQuery
+	}
+
+	parsed.User = nil
+
+	if parsed.RawQuery != "" {
+		query := parsed.Query()
+		for _, values := range query {
+			for i := range values {
+				values[i] = "redacted"
+			}
+		}
+		parsed.RawQuery = query.Encode()
+	}
+
+	return parsed.String()
+}
+
 func statusCodeToText(status int, timedOut bool, err error, altStatusCodes []int) string {
 	// Handle timeout
 	if timedOut {
@@ -302,40 +862,215 @@ func statusCodeToStyle(status int, timedOut bool, err error, altStatusCodes []in
 		return "unknown"
 	}
 
-	// Success: 200 or in alt status codes list
-	if status == 200 || slices.Contains(altStatusCodes, status) {
-		return "ok"
-	}
-
 	// Error: timeout, connection error, or bad status code
-	if timedOut || err != nil || status >= 400 {
+	if timedOut || err != nil || !isSuccessfulStatus(status, altStatusCodes) {
 		return "error"
 	}
 
-	// Other 2xx, 3xx codes
-	if status >= 200 && status < 400 {
-		return "ok"
-	}
+	return "ok"
+}
 
-	return "error"
+// isSuccessfulStatus reports whether an HTTP status code means that a site is
+// up, which is any 2xx or 3xx code as well as the alt-status-codes of the site.
+func isSuccessfulStatus(status int, altStatusCodes []int) bool {
+	return (status >= 200 && status < 400) || slices.Contains(altStatusCodes, status)
 }
 
 type SiteStatusRequest struct {
-	DefaultURL    string        `yaml:"url"`
-	CheckURL      string        `yaml:"check-url"`
-	AllowInsecure bool          `yaml:"allow-insecure"`
-	Timeout       durationField `yaml:"timeout"`
-	BasicAuth     struct {
+	DefaultURL         string            `yaml:"url"`
+	CheckURL           string            `yaml:"check-url"`
+	AllowInsecure      bool              `yaml:"allow-insecure"`
+	Timeout            durationField     `yaml:"timeout"`
+	Method             string            `yaml:"method"`
+	Headers            map[string]string `yaml:"headers"`
+	Body               string            `yaml:"body"`
+	ExpectBodyContains string            `yaml:"expect-body-contains"`
+	ExpectBodyRegex    string            `yaml:"expect-body-regex"`
+	ExpectJSON         *struct {
+		Path  string    `yaml:"path"`
+		Value yaml.Node `yaml:"value"`
+	} `yaml:"expect-json"`
+	BasicAuth struct {
 		Username string `yaml:"username"`
 		Password string `yaml:"password"`
 	} `yaml:"basic-auth"`
+	expectBodyRegex *regexp.Regexp `yaml:"-"`
+	expectJSONPath  jsonPath       `yaml:"-"`
+	expectJSONValue []byte         `yaml:"-"` // JSON encoded, nil if only the path has to exist
+}
+
+const monitorMaxResponseBodySize = 1 << 20
+
+func (r *SiteStatusRequest) checkedURL() string {
+	return ternary(r.CheckURL != "", r.CheckURL, r.DefaultURL)
+}
+
+func (r *SiteStatusRequest) hasBodyAssertions() bool {
+	return r.ExpectBodyContains != "" || r.expectBodyRegex != nil || r.ExpectJSON != nil
+}
+
+// initialize validates the HTTP options. Only called once the URLs are known
+// to be valid.
+func (r *SiteStatusRequest) initialize() error {
+	isHTTP := strings.HasPrefix(r.checkedURL(), "http://") || strings.HasPrefix(r.checkedURL(), "https://")
+	hasHTTPOptions := r.Method != "" || len(r.Headers) > 0 || r.Body != "" ||
+		r.ExpectBodyContains != "" || r.ExpectBodyRegex != "" || r.ExpectJSON != nil
+
+	if hasHTTPOptions && !isHTTP {
+		return errors.New("method, headers, body and expect-* options only apply to http(s) checks")
+	}
+
+	r.Method = strings.ToUpper(cmp.Or(r.Method, http.MethodGet))
+
+	if r.ExpectBodyRegex != "" {
+		pattern, err := regexp.Compile(r.ExpectBodyRegex)
+		if err != nil {
+			return fmt.Errorf("expect-body-regex: %v", err)
+		}
+		r.expectBodyRegex = pattern
+	}
+
+	if r.ExpectJSON != nil {
+		if r.ExpectJSON.Path == "" {
+			return errors.New("expect-json: path is required")
+		}
+
+		path, err := parseJSONPath(r.ExpectJSON.Path)
+		if err != nil {
+			return fmt.Errorf("expect-json: invalid path %s: %v", r.ExpectJSON.Path, err)
+		}
+		r.expectJSONPath = path
+
+		if r.ExpectJSON.Value.Kind != 0 {
+			var value any
+			if err := r.ExpectJSON.Value.Decode(&value); err != nil {
+				return fmt.Errorf("expect-json: value: %v", err)
+			}
+
+			if r.expectJSONValue, err = json.Marshal(value); err != nil {
+				return fmt.Errorf("expect-json: value: %v", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkBody returns an error describing the first assertion the body fails.
+func (r *SiteStatusRequest) checkBody(body []byte) error {
+	if r.ExpectBodyContains != "" && !bytes.Contains(body, []byte(r.ExpectBodyContains)) {
+		return fmt.Errorf("response body does not contain %q", r.ExpectBodyContains)
+	}
+
+	if r.expectBodyRegex != nil && !r.expectBodyRegex.Match(body) {
+		return fmt.Errorf("response body does not match %s", r.ExpectBodyRegex)
+	}
+
+	if r.ExpectJSON != nil {
+		var decoded any
+		if err := json.Unmarshal(body, &decoded); err != nil {
+			return fmt.Errorf("response body is not valid JSON: %v", err)
+		}
+
+		value, exists := r.expectJSONPath.lookup(decoded)
+		if !exists {
+			return fmt.Errorf("%s not found in response", r.ExpectJSON.Path)
+		}
+
+		if r.expectJSONValue != nil {
+			actual, _ := json.Marshal(value)
+			if !bytes.Equal(actual, r.expectJSONValue) {
+				return fmt.Errorf("%s is %s, expected %s", r.ExpectJSON.Path, actual, r.expectJSONValue)
+			}
+		}
+	}
+
+	return nil
 }
 
 type siteStatus struct {
-	Code         int
-	TimedOut     bool
-	ResponseTime time.Duration
-	Error        error
+	Code               int
+	Text               string // result of non-HTTP checks, shown instead of the status code
+	TimedOut           bool
+	ResponseTime       time.Duration
+	Error              error
+	Certificate        *siteCertificate // nil unless the check used TLS
+	CertificateExpired bool
+}
+
+// isUp reports whether the check succeeded. It decides the history, stats,
+// alerts and metrics of a site, so it has to agree with statusCodeToStyle and
+// the styles of the other checks, including sites with expired certificates
+// counting as down.
+func (s *siteStatus) isUp(altStatusCodes []int) bool {
+	if s.Error != nil || s.CertificateExpired {
+		return false
+	}
+
+	// Non-HTTP checks describe their own result
+	return s.Text != "" || isSuccessfulStatus(s.Code, altStatusCodes)
+}
+
+const defaultCertExpiryWarning = 14 * 24 * time.Hour
+
+// siteCertificate describes the leaf certificate presented by a site.
+type siteCertificate struct {
+	NotAfter time.Time
+	Issuer   string
+	DNSNames []string
+}
+
+func newSiteCertificate(certs []*x509.Certificate) *siteCertificate {
+	if len(certs) == 0 {
+		return nil
+	}
+
+	leaf := certs[0]
+	issuer := leaf.Issuer.CommonName
+	if issuer == "" && len(leaf.Issuer.Organization) > 0 {
+		issuer = leaf.Issuer.Organization[0]
+	}
+
+	return &siteCertificate{
+		NotAfter: leaf.NotAfter,
+		Issuer:   issuer,
+		DNSNames: leaf.DNSNames,
+	}
+}
+
+func (c *siteCertificate) Expired() bool {
+	return time.Now().After(c.NotAfter)
+}
+
+func (c *siteCertificate) DaysLeft() int {
+	return int(math.Floor(time.Until(c.NotAfter).Hours() / 24))
+}
+
+// setCertificate records the certificate presented during a TLS handshake.
+// On failure it is taken from the verification error, so that an expired
+// certificate can be told apart from a site that can't be reached at all.
+func (s *siteStatus) setCertificate(state *tls.ConnectionState, err error) {
+	if err != nil {
+		var verificationErr *tls.CertificateVerificationError
+		if errors.As(err, &verificationErr) {
+			s.Certificate = newSiteCertificate(verificationErr.UnverifiedCertificates)
+		}
+
+		var invalidErr x509.CertificateInvalidError
+		s.CertificateExpired = errors.As(err, &invalidErr) && invalidErr.Reason == x509.Expired
+		return
+	}
+
+	if state == nil {
+		return
+	}
+
+	s.Certificate = newSiteCertificate(state.PeerCertificates)
+	// Not verified when allow-insecure is set
+	s.CertificateExpired = s.Certificate != nil && s.Certificate.Expired()
+	if s.CertificateExpired {
+		s.Error = errors.New("certificate has expired")
+	}
 }
 
 func fetchSiteStatusTask(statusRequest *SiteStatusRequest) (siteStatus, error) {
@@ -350,10 +1085,31 @@ func fetchSiteStatusTask(statusRequest *SiteStatusRequest) (siteStatus, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	switch scheme, _, _ := strings.Cut(url, "://"); scheme {
+	case "tcp":
+		return fetchTCPStatus(ctx, url), nil
+	case "tls":
+		return fetchTLSStatus(ctx, url, statusRequest.AllowInsecure), nil
+	case "dns":
+		return fetchDNSStatus(ctx, url), nil
+	}
+
+	var body io.Reader
+	if statusRequest.Body != "" {
+		body = strings.NewReader(statusRequest.Body)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, cmp.Or(statusRequest.Method, http.MethodGet), url, body)
 	if err != nil {
 		return siteStatus{Error: err}, nil
 	}
+	for key, value := range statusRequest.Headers {
+		if strings.EqualFold(key, "Host") {
+			request.Host = value
+			continue
+		}
+		request.Header.Set(key, value)
+	}
 	if statusRequest.BasicAuth.Username != "" || statusRequest.BasicAuth.Password != "" {
 		request.SetBasicAuth(statusRequest.BasicAuth.Username, statusRequest.BasicAuth.Password)
 	}
@@ -372,13 +1128,207 @@ func fetchSiteStatusTask(statusRequest *SiteStatusRequest) (siteStatus, error) {
 			status.TimedOut = true
 		}
 		status.Error = err
+		status.setCertificate(nil, err)
 		return status, nil
 	}
 	defer response.Body.Close()
 	status.Code = response.StatusCode
+	status.setCertificate(response.TLS, nil)
+	if status.CertificateExpired {
+		return status, nil
+	}
+
+	// Error responses are already reported by their status code
+	if statusRequest.hasBodyAssertions() && response.StatusCode < 400 {
+		responseBody, err := io.ReadAll(io.LimitReader(response.Body, monitorMaxResponseBodySize))
+		if err == nil {
+			err = statusRequest.checkBody(responseBody)
+		} else {
+			status.TimedOut = isTimeoutError(err)
+		}
+
+		if err != nil {
+			status.Text = "Assertion Failed"
+			status.Error = err
+		}
+	}
+
 	return status, nil
 }
 
+var monitorURLSchemes = []string{"http", "https", "tcp", "tls", "dns"}
+
+var dnsRecordTypes = []string{"A", "AAAA", "CNAME", "MX", "NS", "TXT"}
+
+func validateMonitorURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	switch parsed.Scheme {
+	case "http", "https":
+		return nil
+	case "tcp", "tls":
+		if parsed.Hostname() == "" {
+			return errors.New("host is required")
+		}
+		if parsed.Scheme == "tcp" && parsed.Port() == "" {
+			return errors.New("port is required, e.g. tcp://localhost:5432")
+		}
+		return nil
+	case "dns":
+		if strings.Trim(parsed.Path, "/") == "" {
+			return errors.New("name to resolve is required, e.g. dns://1.1.1.1/example.com?type=A")
+		}
+		if recordType := dnsQueryType(parsed); !slices.Contains(dnsRecordTypes, recordType) {
+			return fmt.Errorf("unsupported DNS record type %s, must be one of %s", recordType, strings.Join(dnsRecordTypes, ", "))
+		}
+		return nil
+	}
+
+	return fmt.Errorf("must start with one of %s://", strings.Join(monitorURLSchemes, "://, "))
+}
+
+func dnsQueryType(u *url.URL) string {
+	return strings.ToUpper(cmp.Or(u.Query().Get("type"), "A"))
+}
+
+func isTimeoutError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+func fetchTCPStatus(ctx context.Context, rawURL string) siteStatus {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return siteStatus{Error: err}
+	}
+
+	dialer := net.Dialer{}
+	startedAt := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", parsed.Host)
+	status := siteStatus{ResponseTime: time.Since(startedAt)}
+	if err != nil {
+		status.TimedOut = isTimeoutError(err)
+		status.Error = err
+		return status
+	}
+	conn.Close()
+
+	status.Text = "Open"
+	return status
+}
+
+// fetchTLSStatus connects and completes a TLS handshake, verifying the
+// certificate unless allow-insecure is set. The port defaults to 443.
+func fetchTLSStatus(ctx context.Context, rawURL string, allowInsecure bool) siteStatus {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return siteStatus{Error: err}
+	}
+
+	address := parsed.Host
+	if parsed.Port() == "" {
+		address = net.JoinHostPort(parsed.Hostname(), "443")
+	}
+
+	dialer := net.Dialer{}
+	startedAt := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return siteStatus{ResponseTime: time.Since(startedAt), TimedOut: isTimeoutError(err), Error: err}
+	}
+	defer conn.Close()
+
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         parsed.Hostname(),
+		InsecureSkipVerify: allowInsecure,
+	})
+
+	err = tlsConn.HandshakeContext(ctx)
+	status := siteStatus{ResponseTime: time.Since(startedAt)}
+	if err != nil {
+		status.TimedOut = isTimeoutError(err)
+		status.Text = "Handshake Failed"
+		status.Error = err
+		status.setCertificate(nil, err)
+		return status
+	}
+
+	state := tlsConn.ConnectionState()
+	status.Text = tls.VersionName(state.Version)
+	status.setCertificate(&state, nil)
+	return status
+}
+
+// fetchDNSStatus resolves the name in the URL's path. The host is the DNS
+// server to query, the system resolver is used when it's empty (dns:///name).
+func fetchDNSStatus(ctx context.Context, rawURL string) siteStatus {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return siteStatus{Error: err}
+	}
+
+	resolver := net.DefaultResolver
+	if parsed.Host != "" {
+		server := parsed.Host
+		if parsed.Port() == "" {
+			server = net.JoinHostPort(parsed.Hostname(), "53")
+		}
+
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				dialer := net.Dialer{}
+				return dialer.DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	name := strings.Trim(parsed.Path, "/")
+	recordType := dnsQueryType(parsed)
+	startedAt := time.Now()
+	var records int
+
+	switch recordType {
+	case "A", "AAAA":
+		var ips []net.IP
+		ips, err = resolver.LookupIP(ctx, ternary(recordType == "A", "ip4", "ip6"), name)
+		records = len(ips)
+	case "CNAME":
+		_, err = resolver.LookupCNAME(ctx, name)
+		records = 1
+	case "MX":
+		var mx []*net.MX
+		mx, err = resolver.LookupMX(ctx, name)
+		records = len(mx)
+	case "NS":
+		var ns []*net.NS
+		ns, err = resolver.LookupNS(ctx, name)
+		records = len(ns)
+	case "TXT":
+		var txt []string
+		txt, err = resolver.LookupTXT(ctx, name)
+		records = len(txt)
+	}
+
+	status := siteStatus{ResponseTime: time.Since(startedAt)}
+	if err == nil && records == 0 {
+		err = fmt.Errorf("no %s records found for %s", recordType, name)
+	}
+
+	if err != nil {
+		status.TimedOut = isTimeoutError(err)
+		status.Text = "Lookup Failed"
+		status.Error = err
+		return status
+	}
+
+	status.Text = fmt.Sprintf("%d %s record%s", records, recordType, ternary(records == 1, "", "s"))
+	return status
+}
+
 func fetchStatusForSites(requests []*SiteStatusRequest) ([]siteStatus, error) {
 	// Scale workers dynamically: 1-20 based on site count
 	workerCount := min(20, max(1, len(requests)))
@@ -0,0 +1,43 @@
+package dashdashdash
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSiteStatusIsUpAgreesWithStyle(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         siteStatus
+		altStatusCodes []int
+		up             bool
+	}{
+		{"200", siteStatus{Code: 200}, nil, true},
+		{"204", siteStatus{Code: 204}, nil, true},
+		{"301", siteStatus{Code: 301}, nil, true},
+		{"404", siteStatus{Code: 404}, nil, false},
+		{"401 in alt-status-codes", siteStatus{Code: 401}, []int{401}, true},
+		{"503", siteStatus{Code: 503}, []int{401}, false},
+		{"connection error", siteStatus{Error: errors.New("refused")}, nil, false},
+		{"timeout", siteStatus{TimedOut: true, Error: errors.New("deadline exceeded")}, nil, false},
+		{"timeout after a 200", siteStatus{Code: 200, TimedOut: true, Error: errors.New("deadline exceeded")}, nil, false},
+		{"expired certificate", siteStatus{Code: 200, CertificateExpired: true}, nil, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if up := test.status.isUp(test.altStatusCodes); up != test.up {
+				t.Errorf("expected isUp to be %t, got %t", test.up, up)
+			}
+
+			if test.status.CertificateExpired {
+				return
+			}
+
+			style := statusCodeToStyle(test.status.Code, test.status.TimedOut, test.status.Error, test.altStatusCodes)
+			if (style == "ok") != test.up {
+				t.Errorf("style %q doesn't agree with isUp %t", style, test.up)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package dashdashdash
+
+import (
+	"context"
+	"html/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var pluginWidgetTemplate = mustParseTemplate("plugin.html", "widget-base.html")
+
+const defaultPluginCacheDuration = time.Hour
+
+// PluginWidget is implemented by widget types that are registered from outside
+// of this package with RegisterPluginWidget. The widget's options are decoded
+// from its config into it with YAML tags, the options shared by all widgets
+// such as title, cache and css-class are handled by the dashboard.
+type PluginWidget interface {
+	// Initialize validates the options and sets defaults, an error stops the
+	// config from loading.
+	Initialize() error
+
+	// Update fetches the widget's data. It's called again once the cache
+	// duration passes, sooner after an error.
+	Update(ctx context.Context) error
+
+	// Render returns the content of the widget, which is shown as it is.
+	Render() template.HTML
+}
+
+// PluginWidgetDefaults can be implemented by a PluginWidget to change the
+// title and cache duration used when the config doesn't set them.
+type PluginWidgetDefaults interface {
+	DefaultTitle() string
+	DefaultCacheDuration() time.Duration
+}
+
+// RegisterPluginWidget makes a widget type implemented outside of this
+// package available to the config under the given name.
+func RegisterPluginWidget(name string, factory func() PluginWidget) {
+	if factory == nil {
+		RegisterWidget(name, nil)
+		return
+	}
+
+	RegisterWidget(name, func() widget {
+		return &pluginWidget{plugin: factory()}
+	})
+}
+
+// pluginWidget adapts a PluginWidget to the widget interface.
+type pluginWidget struct {
+	widgetBase `yaml:",inline"`
+	Content    template.HTML `yaml:"-"`
+	plugin     PluginWidget
+}
+
+func (widget *pluginWidget) UnmarshalYAML(node *yaml.Node) error {
+	if err := node.Decode(&widget.widgetBase); err != nil {
+		return err
+	}
+
+	return node.Decode(widget.plugin)
+}
+
+func (widget *pluginWidget) IsRefreshable() bool {
+	return true
+}
+
+func (widget *pluginWidget) initialize() error {
+	title, cacheDuration := widget.Type, defaultPluginCacheDuration
+	if defaults, ok := widget.plugin.(PluginWidgetDefaults); ok {
+		title, cacheDuration = defaults.DefaultTitle(), defaults.DefaultCacheDuration()
+	}
+
+	widget.withTitle(title).withCacheDuration(cacheDuration)
+
+	return widget.plugin.Initialize()
+}
+
+func (widget *pluginWidget) update(ctx context.Context) {
+	err := widget.plugin.Update(ctx)
+	widget.canContinueUpdateAfterHandlingErr(err)
+}
+
+func (widget *pluginWidget) Render() template.HTML {
+	widget.Content = widget.plugin.Render()
+	return widget.renderTemplate(widget, pluginWidgetTemplate)
+}
@@ -124,6 +124,36 @@ func (widget *rssWidget) Render() template.HTML {
 	return widget.renderTemplate(widget, rssWidgetTemplate)
 }
 
+// Feed URLs and request headers are left out since they may contain tokens.
+func (widget *rssWidget) data() any {
+	type itemData struct {
+		ChannelName string    `json:"channel_name"`
+		ChannelURL  string    `json:"channel_url"`
+		Title       string    `json:"title"`
+		Link        string    `json:"link"`
+		ImageURL    string    `json:"image_url"`
+		Categories  []string  `json:"categories"`
+		Description string    `json:"description"`
+		PublishedAt time.Time `json:"published_at"`
+	}
+
+	items := make([]itemData, 0, len(widget.Items))
+	for _, item := range widget.Items {
+		items = append(items, itemData{
+			ChannelName: item.ChannelName,
+			ChannelURL:  item.ChannelURL,
+			Title:       item.Title,
+			Link:        item.Link,
+			ImageURL:    item.ImageURL,
+			Categories:  item.Categories,
+			Description: item.Description,
+			PublishedAt: item.PublishedAt,
+		})
+	}
+
+	return map[string]any{"items": items}
+}
+
 type cachedRSSFeed struct {
 	etag         string
 	lastModified string
@@ -178,6 +208,8 @@ func (widget *rssWidget) fetchItemsFromFeeds(ctx context.Context) (rssFeedItemLi
 	seen := make(map[string]struct{})
 
 	for i := range feeds {
+		recordFeedFetch(requests[i].URL, errs[i] != nil)
+
 		if errs[i] != nil {
 			failed++
 			slog.Error("Failed to get RSS feed", "url", requests[i].URL, "error", errs[i])
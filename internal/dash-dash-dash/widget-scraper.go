@@ -87,6 +87,32 @@ func (widget *scraperWidget) Render() template.HTML {
 	return widget.renderTemplate(widget, scraperWidgetTemplate)
 }
 
+func (widget *scraperWidget) data() any {
+	type itemData struct {
+		Title  string   `json:"title"`
+		URL    string   `json:"url"`
+		Values []string `json:"values"`
+		Error  *string  `json:"error"`
+	}
+
+	items := make([]itemData, 0, len(widget.ScrapedData))
+	for _, result := range widget.ScrapedData {
+		item := itemData{
+			Title:  result.Title,
+			URL:    redactURL(result.URL),
+			Values: result.Values,
+		}
+
+		if result.Error != "" {
+			item.Error = &result.Error
+		}
+
+		items = append(items, item)
+	}
+
+	return map[string]any{"items": items}
+}
+
 func (widget *scraperWidget) scrapeItems(ctx context.Context) ([]scraperResult, error) {
 	job := newJob(func(item scraperItem) (scraperResult, error) {
 		return widget.scrapeItemTask(ctx, item)
@@ -74,3 +74,21 @@ func (widget *searchWidget) initialize() error {
 func (widget *searchWidget) Render() template.HTML {
 	return widget.cachedHTML
 }
+
+func (widget *searchWidget) data() any {
+	type bangData struct {
+		Title    string `json:"title"`
+		Shortcut string `json:"shortcut"`
+		URL      string `json:"url"`
+	}
+
+	bangs := make([]bangData, 0, len(widget.Bangs))
+	for _, bang := range widget.Bangs {
+		bangs = append(bangs, bangData{Title: bang.Title, Shortcut: bang.Shortcut, URL: bang.URL})
+	}
+
+	return map[string]any{
+		"search_engine": widget.SearchEngine,
+		"bangs":         bangs,
+	}
+}
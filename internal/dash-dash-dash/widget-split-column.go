@@ -0,0 +1,70 @@
+package dashdashdash
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+)
+
+var splitColumnWidgetTemplate = mustParseTemplate("split-column.html", "widget-base.html")
+
+const (
+	defaultSplitColumnMaxColumns = 2
+	splitColumnMinColumns        = 2
+	splitColumnMaxColumns        = 4
+)
+
+// splitColumnWidget lays out its widgets side by side in sub-columns, so that
+// small widgets don't take up the whole width of a column.
+type splitColumnWidget struct {
+	widgetBase `yaml:",inline"`
+	MaxColumns int     `yaml:"max-columns"`
+	Masonry    bool    `yaml:"masonry"`
+	Widgets    widgets `yaml:"widgets"`
+}
+
+func (widget *splitColumnWidget) childWidgets() []widget {
+	return widget.Widgets
+}
+
+func (widget *splitColumnWidget) initialize() error {
+	widget.withTitle("Split Column").withError(nil)
+	widget.HideHeader = true
+
+	if widget.MaxColumns == 0 {
+		widget.MaxColumns = defaultSplitColumnMaxColumns
+	}
+
+	if widget.MaxColumns < splitColumnMinColumns || widget.MaxColumns > splitColumnMaxColumns {
+		return fmt.Errorf("max-columns must be between %d and %d", splitColumnMinColumns, splitColumnMaxColumns)
+	}
+
+	if len(widget.Widgets) == 0 {
+		return errors.New("at least one widget is required")
+	}
+
+	for _, child := range widget.Widgets {
+		if _, ok := child.(*splitColumnWidget); ok {
+			return errors.New("split-column widgets can't be nested")
+		}
+
+		if err := child.initialize(); err != nil {
+			return formatWidgetInitError(err, child)
+		}
+	}
+
+	return nil
+}
+
+func (widget *splitColumnWidget) Render() template.HTML {
+	return widget.renderTemplate(widget, splitColumnWidgetTemplate)
+}
+
+func (widget *splitColumnWidget) data() any {
+	children := make([]widgetDataResponse, 0, len(widget.Widgets))
+	for _, child := range widget.Widgets {
+		children = append(children, newWidgetDataResponse(child))
+	}
+
+	return map[string]any{"widgets": children}
+}
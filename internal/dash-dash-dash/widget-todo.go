@@ -1,11 +1,22 @@
 package dashdashdash
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"html/template"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
 )
 
 var todoWidgetTemplate = mustParseTemplate("todo.html", "widget-base.html")
 
+const todoStorageFile = "todo.json"
+
+var errTodoItemNotFound = errors.New("item not found")
+
 type todoWidget struct {
 	widgetBase `yaml:",inline"`
 	cachedHTML template.HTML `yaml:"-"`
@@ -19,10 +30,333 @@ func (widget *todoWidget) initialize() error {
 		widget.TodoID = "default"
 	}
 
-	widget.cachedHTML = widget.renderTemplate(widget, todoWidgetTemplate)
 	return nil
 }
 
+// Rendered here rather than in initialize since whether the items are synced
+// with the server depends on the data storage provider.
+func (widget *todoWidget) setProviders(providers *widgetProviders) {
+	widget.widgetBase.setProviders(providers)
+	widget.cachedHTML = widget.renderTemplate(widget, todoWidgetTemplate)
+}
+
 func (widget *todoWidget) Render() template.HTML {
 	return widget.cachedHTML
 }
+
+// Items are only known to the server when they're synced, otherwise they
+// live in the browser.
+func (widget *todoWidget) data() any {
+	response := map[string]any{
+		"id":     widget.TodoID,
+		"synced": widget.SyncEnabled(),
+		"items":  nil,
+	}
+
+	if widget.SyncEnabled() {
+		if store, err := todoStoreFor(widget.Providers.dataStorage); err == nil {
+			response["items"] = store.list(widget.TodoID)
+		}
+	}
+
+	return response
+}
+
+func (widget *todoWidget) SyncEnabled() bool {
+	return widget.Providers != nil && widget.Providers.dataStorage != nil
+}
+
+func (widget *todoWidget) handleRequest(w http.ResponseWriter, r *http.Request, path string) {
+	if !widget.SyncEnabled() {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Server-side storage is not enabled"))
+		return
+	}
+
+	store, err := todoStoreFor(widget.Providers.dataStorage)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	id := widget.TodoID
+
+	switch {
+	case path == "items" && r.Method == http.MethodGet:
+		writeJSONResponse(w, http.StatusOK, store.list(id))
+
+	case path == "items" && r.Method == http.MethodPost:
+		item, err := decodeJSONRequestBody[todoItem](w, r)
+		if err != nil {
+			writeTodoError(w, err)
+			return
+		}
+
+		prepend := r.URL.Query().Get("position") == "first"
+		item, err = store.add(id, item, prepend)
+		if err != nil {
+			writeTodoError(w, err)
+			return
+		}
+
+		writeJSONResponse(w, http.StatusCreated, item)
+
+	case path == "items" && r.Method == http.MethodPut:
+		items, err := decodeJSONRequestBody[[]todoItem](w, r)
+		if err != nil {
+			writeTodoError(w, err)
+			return
+		}
+
+		items, err = store.replace(id, items)
+		if err != nil {
+			writeTodoError(w, err)
+			return
+		}
+
+		writeJSONResponse(w, http.StatusOK, items)
+
+	case path == "items/order" && r.Method == http.MethodPut:
+		order, err := decodeJSONRequestBody[[]string](w, r)
+		if err != nil {
+			writeTodoError(w, err)
+			return
+		}
+
+		items, err := store.reorder(id, order)
+		if err != nil {
+			writeTodoError(w, err)
+			return
+		}
+
+		writeJSONResponse(w, http.StatusOK, items)
+
+	case strings.HasPrefix(path, "items/") && r.Method == http.MethodPatch:
+		changes, err := decodeJSONRequestBody[todoItemChanges](w, r)
+		if err != nil {
+			writeTodoError(w, err)
+			return
+		}
+
+		item, err := store.modify(id, strings.TrimPrefix(path, "items/"), changes)
+		if err != nil {
+			writeTodoError(w, err)
+			return
+		}
+
+		writeJSONResponse(w, http.StatusOK, item)
+
+	case strings.HasPrefix(path, "items/") && r.Method == http.MethodDelete:
+		if err := store.remove(id, strings.TrimPrefix(path, "items/")); err != nil {
+			writeTodoError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Not found"))
+	}
+}
+
+func writeTodoError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errTodoItemNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, errInvalidRequestBody):
+		w.WriteHeader(http.StatusBadRequest)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	w.Write([]byte(err.Error()))
+}
+
+type todoItem struct {
+	ID      string `json:"id"`
+	Text    string `json:"text"`
+	Checked bool   `json:"checked"`
+}
+
+type todoItemChanges struct {
+	Text    *string `json:"text"`
+	Checked *bool   `json:"checked"`
+}
+
+func newTodoItemID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// todoStore holds the items of every to-do list, keyed by the widget's id
+// property. Stores are shared between config reloads so that in flight
+// requests and the new config see the same items.
+type todoStore struct {
+	mu      sync.Mutex
+	storage *dataStorage
+	lists   map[string][]todoItem
+}
+
+var todoStores = struct {
+	mu     sync.Mutex
+	byPath map[string]*todoStore
+}{
+	byPath: make(map[string]*todoStore),
+}
+
+func todoStoreFor(storage *dataStorage) (*todoStore, error) {
+	todoStores.mu.Lock()
+	defer todoStores.mu.Unlock()
+
+	if store, exists := todoStores.byPath[storage.path]; exists {
+		return store, nil
+	}
+
+	store := &todoStore{storage: storage}
+	if _, err := storage.load(todoStorageFile, &store.lists); err != nil {
+		return nil, err
+	}
+
+	if store.lists == nil {
+		store.lists = make(map[string][]todoItem)
+	}
+
+	todoStores.byPath[storage.path] = store
+	return store, nil
+}
+
+func (s *todoStore) list(id string) []todoItem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := slices.Clone(s.lists[id])
+	if items == nil {
+		items = []todoItem{}
+	}
+
+	return items
+}
+
+// update applies fn to a copy of the list and only keeps the result if it
+// could be written to disk.
+func (s *todoStore) update(id string, fn func([]todoItem) ([]todoItem, error)) ([]todoItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := s.lists[id]
+	items, err := fn(slices.Clone(previous))
+	if err != nil {
+		return nil, err
+	}
+
+	s.lists[id] = items
+	if err := s.storage.save(todoStorageFile, s.lists); err != nil {
+		s.lists[id] = previous
+		return nil, err
+	}
+
+	return slices.Clone(items), nil
+}
+
+func (s *todoStore) add(id string, item todoItem, prepend bool) (todoItem, error) {
+	_, err := s.update(id, func(items []todoItem) ([]todoItem, error) {
+		if item.ID == "" || slices.ContainsFunc(items, func(i todoItem) bool { return i.ID == item.ID }) {
+			item.ID = newTodoItemID()
+		}
+
+		if prepend {
+			return slices.Insert(items, 0, item), nil
+		}
+
+		return append(items, item), nil
+	})
+
+	return item, err
+}
+
+func (s *todoStore) replace(id string, newItems []todoItem) ([]todoItem, error) {
+	return s.update(id, func([]todoItem) ([]todoItem, error) {
+		seen := make(map[string]bool, len(newItems))
+
+		for i := range newItems {
+			if newItems[i].ID == "" || seen[newItems[i].ID] {
+				newItems[i].ID = newTodoItemID()
+			}
+			seen[newItems[i].ID] = true
+		}
+
+		return newItems, nil
+	})
+}
+
+// reorder moves the items to match the given ids. Items missing from the
+// order, such as ones added from another device in the meantime, are kept at
+// the end.
+func (s *todoStore) reorder(id string, order []string) ([]todoItem, error) {
+	return s.update(id, func(items []todoItem) ([]todoItem, error) {
+		position := make(map[string]int, len(order))
+		for i, itemID := range order {
+			if _, exists := position[itemID]; !exists {
+				position[itemID] = i
+			}
+		}
+
+		slices.SortStableFunc(items, func(a, b todoItem) int {
+			posA, okA := position[a.ID]
+			posB, okB := position[b.ID]
+
+			switch {
+			case okA && okB:
+				return posA - posB
+			case okA:
+				return -1
+			case okB:
+				return 1
+			}
+
+			return 0
+		})
+
+		return items, nil
+	})
+}
+
+func (s *todoStore) modify(id, itemID string, changes todoItemChanges) (todoItem, error) {
+	var modified todoItem
+
+	_, err := s.update(id, func(items []todoItem) ([]todoItem, error) {
+		i := slices.IndexFunc(items, func(item todoItem) bool { return item.ID == itemID })
+		if i == -1 {
+			return nil, errTodoItemNotFound
+		}
+
+		if changes.Text != nil {
+			items[i].Text = *changes.Text
+		}
+
+		if changes.Checked != nil {
+			items[i].Checked = *changes.Checked
+		}
+
+		modified = items[i]
+		return items, nil
+	})
+
+	return modified, err
+}
+
+func (s *todoStore) remove(id, itemID string) error {
+	_, err := s.update(id, func(items []todoItem) ([]todoItem, error) {
+		i := slices.IndexFunc(items, func(item todoItem) bool { return item.ID == itemID })
+		if i == -1 {
+			return nil, errTodoItemNotFound
+		}
+
+		return slices.Delete(items, i, i+1), nil
+	})
+
+	return err
+}
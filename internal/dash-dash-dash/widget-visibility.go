@@ -0,0 +1,234 @@
+package dashdashdash
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// widgetSchedule limits when a widget is shown to certain days and a time of
+// day. A time range that ends before it starts continues past midnight, and
+// belongs to the day it started on.
+type widgetSchedule struct {
+	days     []time.Weekday
+	from     time.Duration
+	to       time.Duration
+	location *time.Location
+}
+
+var weekdaysByName = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+func (s *widgetSchedule) UnmarshalYAML(node *yaml.Node) error {
+	var raw struct {
+		Days     []string `yaml:"days"`
+		From     string   `yaml:"from"`
+		To       string   `yaml:"to"`
+		Timezone string   `yaml:"timezone"`
+	}
+
+	if err := node.Decode(&raw); err != nil {
+		return err
+	}
+
+	for _, name := range raw.Days {
+		day, exists := weekdaysByName[strings.ToLower(name)]
+		if !exists {
+			return fmt.Errorf("invalid day in show-when: %s", name)
+		}
+		s.days = append(s.days, day)
+	}
+
+	var err error
+
+	s.from, err = parseTimeOfDay(raw.From, 0)
+	if err != nil {
+		return fmt.Errorf("invalid from time in show-when: %v", err)
+	}
+
+	s.to, err = parseTimeOfDay(raw.To, 24*time.Hour)
+	if err != nil {
+		return fmt.Errorf("invalid to time in show-when: %v", err)
+	}
+
+	if s.from == s.to {
+		return errors.New("from and to times in show-when must be different")
+	}
+
+	s.location = time.Local
+	if raw.Timezone != "" {
+		s.location, err = time.LoadLocation(raw.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid timezone in show-when: %s", raw.Timezone)
+		}
+	}
+
+	return nil
+}
+
+// parseTimeOfDay parses a 24-hour HH:MM time into the duration since
+// midnight, an empty value results in the fallback.
+func parseTimeOfDay(value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%s, expected HH:MM", value)
+	}
+
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
+
+func (s *widgetSchedule) includes(now time.Time) bool {
+	now = now.In(s.location)
+	day := now.Weekday()
+	sinceMidnight := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute
+
+	if s.from < s.to {
+		return s.includesDay(day) && sinceMidnight >= s.from && sinceMidnight < s.to
+	}
+
+	if sinceMidnight >= s.from {
+		return s.includesDay(day)
+	}
+
+	return sinceMidnight < s.to && s.includesDay((day+6)%7)
+}
+
+func (s *widgetSchedule) includesDay(day time.Weekday) bool {
+	return len(s.days) == 0 || slices.Contains(s.days, day)
+}
+
+// widgetLayouts is a set of the layouts a page is shown with. The layout is
+// picked by the browser from the width of the screen, so it's reported in the
+// layout cookie and any layout is assumed when it's missing.
+type widgetLayouts uint8
+
+const (
+	layoutMobile widgetLayouts = 1 << iota
+	layoutDesktop
+
+	allLayouts = layoutMobile | layoutDesktop
+)
+
+const layoutCookieName = "layout"
+
+var layoutsByName = map[string]widgetLayouts{
+	"mobile":  layoutMobile,
+	"desktop": layoutDesktop,
+}
+
+func layoutsForRequest(r *http.Request) widgetLayouts {
+	cookie, err := r.Cookie(layoutCookieName)
+	if err != nil {
+		return allLayouts
+	}
+
+	if layout, exists := layoutsByName[cookie.Value]; exists {
+		return layout
+	}
+
+	return allLayouts
+}
+
+// widgetDevices lists the layouts a widget is hidden on. The names are kept
+// for the CSS classes that hide the widget if the layout changes after it was
+// rendered.
+type widgetDevices []string
+
+func (d *widgetDevices) UnmarshalYAML(node *yaml.Node) error {
+	var values []string
+
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+
+	for _, value := range values {
+		if _, exists := layoutsByName[value]; !exists {
+			return fmt.Errorf("invalid value in hide-on: %s, expected mobile or desktop", value)
+		}
+	}
+
+	*d = values
+	return nil
+}
+
+// hidesAll reports whether the widget is hidden on every one of the layouts.
+func (d widgetDevices) hidesAll(layouts widgetLayouts) bool {
+	var hidden widgetLayouts
+	for _, name := range d {
+		hidden |= layoutsByName[name]
+	}
+
+	return hidden != 0 && layouts&^hidden == 0
+}
+
+func (w *widgetBase) visibleAt(now time.Time, layouts widgetLayouts) bool {
+	return (w.ShowWhen == nil || w.ShowWhen.includes(now)) && !w.HideOn.hidesAll(layouts)
+}
+
+// isWidgetVisibleAt reports whether the widget is shown at the given time on
+// any of the layouts. Containers are hidden when none of their widgets are
+// shown.
+func isWidgetVisibleAt(w widget, now time.Time, layouts widgetLayouts) bool {
+	if !w.visibleAt(now, layouts) {
+		return false
+	}
+
+	if container, ok := w.(containerWidget); ok {
+		return slices.ContainsFunc(container.childWidgets(), func(child widget) bool {
+			return isWidgetVisibleAt(child, now, layouts)
+		})
+	}
+
+	return true
+}
+
+func visibleWidgetsAt(list []widget, now time.Time, layouts widgetLayouts) []widget {
+	visible := make([]widget, 0, len(list))
+	for _, w := range list {
+		if isWidgetVisibleAt(w, now, layouts) {
+			visible = append(visible, w)
+		}
+	}
+
+	return visible
+}
+
+// visibleWidgets returns the widgets of the page that are currently shown on
+// any of the layouts, including nested ones, so that hidden widgets aren't
+// updated.
+func (p *page) visibleWidgets(now time.Time, layouts widgetLayouts) []widget {
+	all := appendVisibleWidgets(nil, p.HeadWidgets, now, layouts)
+	for c := range p.Columns {
+		all = appendVisibleWidgets(all, p.Columns[c].Widgets, now, layouts)
+	}
+
+	return all
+}
+
+func appendVisibleWidgets(all []widget, list []widget, now time.Time, layouts widgetLayouts) []widget {
+	for _, w := range visibleWidgetsAt(list, now, layouts) {
+		all = append(all, w)
+
+		if container, ok := w.(containerWidget); ok {
+			all = appendVisibleWidgets(all, container.childWidgets(), now, layouts)
+		}
+	}
+
+	return all
+}
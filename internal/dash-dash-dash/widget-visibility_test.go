@@ -0,0 +1,130 @@
+package dashdashdash
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func newTestWidgetSchedule(t *testing.T, config string) *widgetSchedule {
+	t.Helper()
+
+	schedule := &widgetSchedule{}
+	if err := yaml.Unmarshal([]byte(config), schedule); err != nil {
+		t.Fatalf("parsing schedule: %v", err)
+	}
+
+	return schedule
+}
+
+func TestWidgetScheduleIncludes(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data isn't available: %v", err)
+	}
+
+	at := func(value string) time.Time {
+		parsed, err := time.ParseInLocation("2006-01-02 15:04", value, berlin)
+		if err != nil {
+			t.Fatalf("parsing %s: %v", value, err)
+		}
+		return parsed
+	}
+
+	utc := func(value string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatalf("parsing %s: %v", value, err)
+		}
+		return parsed
+	}
+
+	businessHours := "{days: [mon, tue, wed, thu, fri], from: \"08:00\", to: \"18:00\", timezone: Europe/Berlin}"
+	overnight := "{days: [fri], from: \"22:00\", to: \"06:00\", timezone: Europe/Berlin}"
+	allDay := "{days: [monday], timezone: Europe/Berlin}"
+	earlyMorning := "{from: \"01:00\", to: \"03:00\", timezone: Europe/Berlin}"
+	duplicatedHour := "{from: \"02:00\", to: \"03:00\", timezone: Europe/Berlin}"
+
+	// 2026-10-16 is a Friday
+	tests := []struct {
+		name     string
+		schedule string
+		now      time.Time
+		included bool
+	}{
+		{"start of business hours", businessHours, at("2026-10-16 08:00"), true},
+		{"end of business hours", businessHours, at("2026-10-16 18:00"), false},
+		{"weekend", businessHours, at("2026-10-17 12:00"), false},
+		{"in another time zone", businessHours, utc("2026-10-16T06:30:00Z"), true},
+		{"before an overnight range", overnight, at("2026-10-16 21:59"), false},
+		{"overnight range before midnight", overnight, at("2026-10-16 23:00"), true},
+		{"overnight range past midnight", overnight, at("2026-10-17 05:59"), true},
+		{"end of an overnight range", overnight, at("2026-10-17 06:00"), false},
+		{"overnight range started the day before", overnight, at("2026-10-16 05:00"), false},
+		{"overnight range on the next evening", overnight, at("2026-10-17 22:30"), false},
+		{"start of the day", allDay, at("2026-10-19 00:00"), true},
+		{"end of the day", allDay, at("2026-10-19 23:59"), true},
+		{"end of the day before", allDay, at("2026-10-18 23:59"), false},
+		{"start of the day after", allDay, at("2026-10-20 00:00"), false},
+		{"before the clocks go forward", earlyMorning, utc("2026-03-29T00:30:00Z"), true},
+		{"after the clocks went forward", earlyMorning, utc("2026-03-29T01:30:00Z"), false},
+		{"first time the hour repeats", duplicatedHour, utc("2026-10-25T00:30:00Z"), true},
+		{"second time the hour repeats", duplicatedHour, utc("2026-10-25T01:30:00Z"), true},
+		{"after the repeated hour", duplicatedHour, utc("2026-10-25T02:30:00Z"), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule := newTestWidgetSchedule(t, test.schedule)
+			if included := schedule.includes(test.now); included != test.included {
+				t.Errorf("expected %t at %s", test.included, test.now.In(berlin))
+			}
+		})
+	}
+}
+
+func TestWidgetScheduleErrors(t *testing.T) {
+	tests := []struct {
+		schedule string
+		err      string
+	}{
+		{"{days: [someday]}", "invalid day in show-when: someday"},
+		{"{from: \"8am\"}", "invalid from time in show-when: 8am, expected HH:MM"},
+		{"{to: \"25:00\"}", "invalid to time in show-when: 25:00, expected HH:MM"},
+		{"{from: \"08:00\", to: \"08:00\"}", "from and to times in show-when must be different"},
+		{"{timezone: Mars/Olympus}", "invalid timezone in show-when: Mars/Olympus"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.schedule, func(t *testing.T) {
+			err := yaml.Unmarshal([]byte(test.schedule), &widgetSchedule{})
+			if err == nil || err.Error() != test.err {
+				t.Errorf("expected error %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestWidgetDevicesHidesAll(t *testing.T) {
+	tests := []struct {
+		name    string
+		hideOn  widgetDevices
+		layouts widgetLayouts
+		hidden  bool
+	}{
+		{"not hidden", nil, layoutMobile, false},
+		{"hidden on mobile", widgetDevices{"mobile"}, layoutMobile, true},
+		{"shown on desktop", widgetDevices{"mobile"}, layoutDesktop, false},
+		{"unknown layout", widgetDevices{"mobile"}, allLayouts, false},
+		{"hidden on both", widgetDevices{"mobile", "desktop"}, allLayouts, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if hidden := test.hideOn.hidesAll(test.layouts); hidden != test.hidden {
+				t.Errorf("expected %t, got %t", test.hidden, hidden)
+			}
+		})
+	}
+}
@@ -91,6 +91,39 @@ func (widget *weatherWidget) Render() template.HTML {
 	return widget.renderTemplate(widget, weatherWidgetTemplate)
 }
 
+func (widget *weatherWidget) data() any {
+	if widget.Weather == nil || widget.Place == nil {
+		return nil
+	}
+
+	type forecastData struct {
+		Time          string `json:"time"`
+		Temperature   int    `json:"temperature"`
+		Precipitation bool   `json:"precipitation"`
+	}
+
+	forecast := make([]forecastData, 0, len(widget.Weather.Columns))
+	for i, column := range widget.Weather.Columns {
+		forecast = append(forecast, forecastData{
+			Time:          widget.TimeLabels[i],
+			Temperature:   column.Temperature,
+			Precipitation: column.HasPrecipitation,
+		})
+	}
+
+	return map[string]any{
+		"location":             widget.Place.Name,
+		"area":                 widget.Place.Area,
+		"country":              widget.Place.Country,
+		"units":                ternary(widget.Units == "imperial", "imperial", "metric"),
+		"temperature":          widget.Weather.Temperature,
+		"apparent_temperature": widget.Weather.ApparentTemperature,
+		"weather_code":         widget.Weather.WeatherCode,
+		"condition":            widget.Weather.WeatherCodeAsString(),
+		"forecast":             forecast,
+	}
+}
+
 type weather struct {
 	Temperature         int
 	ApparentTemperature int
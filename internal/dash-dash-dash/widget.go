@@ -7,7 +7,12 @@ import (
 	"fmt"
 	"html/template"
 	"log/slog"
+	"maps"
 	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -16,38 +21,74 @@ import (
 
 var widgetIDCounter atomic.Uint64
 
+// widgetFactories maps the type names used in the config to the widgets
+// they create. Built-in widgets are registered here, others with
+// RegisterWidget before the config is loaded.
+var widgetFactories = struct {
+	mu        sync.RWMutex
+	factories map[string]func() widget
+}{
+	factories: map[string]func() widget{
+		"clock":             func() widget { return &clockWidget{} },
+		"calendar":          func() widget { return &calendarWidget{} },
+		"search":            func() widget { return &searchWidget{} },
+		"weather":           func() widget { return &weatherWidget{} },
+		"to-do":             func() widget { return &todoWidget{} },
+		"ip-address":        func() widget { return &ipAddressWidget{} },
+		"monitor":           func() widget { return &monitorWidget{} },
+		"bookmarks":         func() widget { return &bookmarksWidget{} },
+		"rss":               func() widget { return &rssWidget{} },
+		"scraper":           func() widget { return &scraperWidget{} },
+		"docker-containers": func() widget { return &dockerContainersWidget{} },
+		"custom-api":        func() widget { return &customAPIWidget{} },
+		"extension":         func() widget { return &extensionWidget{} },
+		"group":             func() widget { return &groupWidget{} },
+		"split-column":      func() widget { return &splitColumnWidget{} },
+	},
+}
+
+// RegisterWidget makes a widget type available to the config under the given
+// name. It panics if the name is empty or already registered, so it's meant
+// to be called on startup, like database/sql.Register.
+func RegisterWidget(name string, factory func() widget) {
+	widgetFactories.mu.Lock()
+	defer widgetFactories.mu.Unlock()
+
+	if name == "" || factory == nil {
+		panic("dash-dash-dash: RegisterWidget called with an empty name or nil factory")
+	}
+
+	if _, exists := widgetFactories.factories[name]; exists {
+		panic("dash-dash-dash: widget type registered twice: " + name)
+	}
+
+	widgetFactories.factories[name] = factory
+}
+
+func registeredWidgetTypes() []string {
+	widgetFactories.mu.RLock()
+	defer widgetFactories.mu.RUnlock()
+
+	return slices.Sorted(maps.Keys(widgetFactories.factories))
+}
+
 func newWidget(widgetType string) (widget, error) {
 	if widgetType == "" {
 		return nil, errors.New("widget 'type' property is empty or not specified")
 	}
 
-	var w widget
-
-	switch widgetType {
-	case "clock":
-		w = &clockWidget{}
-	case "calendar":
-		w = &calendarWidget{}
-	case "search":
-		w = &searchWidget{}
-	case "weather":
-		w = &weatherWidget{}
-	case "to-do":
-		w = &todoWidget{}
-	case "ip-address":
-		w = &ipAddressWidget{}
-	case "monitor":
-		w = &monitorWidget{}
-	case "bookmarks":
-		w = &bookmarksWidget{}
-	case "rss":
-		w = &rssWidget{}
-	case "scraper":
-		w = &scraperWidget{}
-	default:
-		return nil, fmt.Errorf("unknown widget type: %s", widgetType)
+	widgetFactories.mu.RLock()
+	factory, exists := widgetFactories.factories[widgetType]
+	widgetFactories.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf(
+			"unknown widget type: %s, available types: %s",
+			widgetType, strings.Join(registeredWidgetTypes(), ", "),
+		)
 	}
 
+	w := factory()
 	w.setID(widgetIDCounter.Add(1))
 
 	return w, nil
@@ -97,6 +138,16 @@ type widget interface {
 	setProviders(*widgetProviders)
 	update(context.Context)
 	setID(uint64)
+	state() widgetState
+	recordUpdate(time.Duration)
+	getUpdateStats() widgetUpdateStats
+	visibleAt(time.Time, widgetLayouts) bool
+}
+
+// widgetRequestHandler is implemented by widgets that expose their own API
+// under /api/widgets/{widget}/{path...}.
+type widgetRequestHandler interface {
+	handleRequest(w http.ResponseWriter, r *http.Request, path string)
 }
 
 type cacheType int
@@ -108,26 +159,30 @@ const (
 )
 
 type widgetBase struct {
-	ID                  uint64           `yaml:"-"`
-	Providers           *widgetProviders `yaml:"-"`
-	Type                string           `yaml:"type"`
-	Title               string           `yaml:"title"`
-	TitleURL            string           `yaml:"title-url"`
-	HideHeader          bool             `yaml:"hide-header"`
-	CSSClass            string           `yaml:"css-class"`
-	CustomCacheDuration durationField    `yaml:"cache"`
-	ContentAvailable    bool             `yaml:"-"`
-	Error               error            `yaml:"-"`
-	Notice              error            `yaml:"-"`
-	templateBuffer      bytes.Buffer     `yaml:"-"`
-	cacheDuration       time.Duration    `yaml:"-"`
-	cacheType           cacheType        `yaml:"-"`
-	nextUpdate          time.Time        `yaml:"-"`
-	updateRetriedTimes  int              `yaml:"-"`
+	ID                  uint64            `yaml:"-"`
+	Providers           *widgetProviders  `yaml:"-"`
+	Type                string            `yaml:"type"`
+	Title               string            `yaml:"title"`
+	TitleURL            string            `yaml:"title-url"`
+	HideHeader          bool              `yaml:"hide-header"`
+	CSSClass            string            `yaml:"css-class"`
+	CustomCacheDuration durationField     `yaml:"cache"`
+	ShowWhen            *widgetSchedule   `yaml:"show-when"`
+	HideOn              widgetDevices     `yaml:"hide-on"`
+	ContentAvailable    bool              `yaml:"-"`
+	Error               error             `yaml:"-"`
+	Notice              error             `yaml:"-"`
+	templateBuffer      bytes.Buffer      `yaml:"-"`
+	cacheDuration       time.Duration     `yaml:"-"`
+	cacheType           cacheType         `yaml:"-"`
+	nextUpdate          time.Time         `yaml:"-"`
+	updateRetriedTimes  int               `yaml:"-"`
+	updateStats         widgetUpdateStats `yaml:"-"`
 }
 
 type widgetProviders struct {
 	assetResolver func(string) string
+	dataStorage   *dataStorage
 }
 
 func (w *widgetBase) requiresUpdate(now *time.Time) bool {
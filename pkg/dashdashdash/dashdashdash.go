@@ -0,0 +1,47 @@
+// Package dashdashdash lets other modules build a dash-dash-dash binary with
+// their own widget types:
+//
+//	package main
+//
+//	import (
+//		"os"
+//
+//		"dash-dash-dash/pkg/dashdashdash"
+//	)
+//
+//	func main() {
+//		dashdashdash.RegisterWidget("hello", func() dashdashdash.Widget {
+//			return &helloWidget{}
+//		})
+//
+//		os.Exit(dashdashdash.Main())
+//	}
+//
+// The widget can then be used in the config with type: hello.
+package dashdashdash
+
+import (
+	internal "dash-dash-dash/internal/dash-dash-dash"
+)
+
+// Widget is implemented by custom widget types. Options from the widget's
+// config are decoded into it using yaml struct tags, title, cache, css-class
+// and the other options shared by all widgets are handled by the dashboard.
+type Widget = internal.PluginWidget
+
+// WidgetDefaults can be implemented by a Widget to set the title and cache
+// duration used when the config doesn't set them.
+type WidgetDefaults = internal.PluginWidgetDefaults
+
+// RegisterWidget makes a widget type available to the config under the given
+// name. It must be called before Main and panics if the name is already taken,
+// including by a built-in widget.
+func RegisterWidget(name string, factory func() Widget) {
+	internal.RegisterPluginWidget(name, factory)
+}
+
+// Main runs dash-dash-dash with the command line arguments of the process and
+// returns its exit code.
+func Main() int {
+	return internal.Main()
+}